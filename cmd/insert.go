@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,21 +19,37 @@ import (
 	"github.com/influxdata/influx-stress/stress"
 	"github.com/influxdata/influx-stress/write"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
 	statsHost, statsDB                   string
 	host, db, rp, precision, consistency string
 	username, password                   string
+	api, org, bucket, token              string
+	format, graphiteTemplate             string
 	createCommand, dump                  string
+	distribution                         string
+	measurements                         []string
+	fromSample                           string
 	seriesN, gzip                        int
+	udpPayload                           int
+	stringLength, stringPool             int
 	batchSize, pointsN, pps              uint64
 	runtime                              time.Duration
 	tick                                 time.Duration
+	churnRate                            float64
+	churnInterval                        time.Duration
+	startTime, endTime                   string
+	interval                             time.Duration
+	lateRate, duplicateRate, futureRate  float64
+	lateOffset, futureOffset             string
+	seed                                 int64
 	fast, quiet                          bool
 	strict, kapacitorMode                bool
 	recordStats                          bool
 	tlsSkipVerify                        bool
+	acceptPartial, noSync                bool
 )
 
 const (
@@ -61,6 +80,136 @@ func insertRun(cmd *cobra.Command, args []string) {
 		fieldStr = args[1]
 	}
 
+	pc, err := lineprotocol.ParsePrecision(precision)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid precision:", err.Error())
+		os.Exit(1)
+		return
+	}
+
+	// Points are spaced apart by the precision, which must not be finer
+	// than the timestamps of the format.
+	if fp := write.FormatPrecision(format); pc.Duration() < fp.Duration() {
+		if cmd.Flags().Changed("precision") {
+			fmt.Fprintf(os.Stderr, "The %s format has timestamps in %s, --precision must be %s or coarser\n", format, fp, fp)
+			os.Exit(1)
+			return
+		}
+		pc = fp
+	}
+
+	// When backfilling, the points are stamped with a clock running from
+	// start to end instead of the current time.
+	var start, end time.Time
+	var clockInterval time.Duration
+	backfill := startTime != ""
+	if backfill {
+		now := time.Now()
+		start, err = parseTime(startTime, now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid start time:", err.Error())
+			os.Exit(1)
+			return
+		}
+
+		end = now
+		if endTime != "" {
+			end, err = parseTime(endTime, now)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Invalid end time:", err.Error())
+				os.Exit(1)
+				return
+			}
+		}
+
+		if !end.After(start) || interval <= 0 {
+			fmt.Fprintln(os.Stderr, "--end-time must be after --start-time, and --interval positive")
+			os.Exit(1)
+			return
+		}
+		clockInterval = interval
+	}
+
+	if lateRate < 0 || duplicateRate < 0 || futureRate < 0 || lateRate+duplicateRate+futureRate > 1 {
+		fmt.Fprintln(os.Stderr, "--late-rate, --duplicate-rate and --future-rate must be positive and add up to at most 1")
+		os.Exit(1)
+		return
+	}
+
+	newLateOffset, err := point.ParseGenerator(lateOffset)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid late offset:", err.Error())
+		os.Exit(1)
+		return
+	}
+
+	newFutureOffset, err := point.ParseGenerator(futureOffset)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid future offset:", err.Error())
+		os.Exit(1)
+		return
+	}
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	templates := []point.Template{}
+	if len(args) >= 1 || len(measurements) == 0 && fromSample == "" {
+		templates = append(templates, point.Template{Series: seriesKey, Fields: fieldStr, SeriesN: seriesN, Weight: 1})
+	}
+	for _, m := range measurements {
+		t, err := point.ParseTemplate(m, defaultFieldStr, seriesN)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid measurement:", err.Error())
+			os.Exit(1)
+			return
+		}
+		templates = append(templates, t)
+	}
+	if fromSample != "" {
+		inferred, err := inferTemplates(fromSample)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid sample:", err.Error())
+			os.Exit(1)
+			return
+		}
+		templates = append(templates, inferred...)
+	}
+
+	// sets[i] holds the points generated from templates[i].
+	sets := make([][]lineprotocol.Point, len(templates))
+	totalSeries := 0
+	for i, t := range templates {
+		sets[i], err = t.NewPoints(point.Config{
+			Precision:    pc,
+			StringLength: stringLength,
+			StringPool:   stringPool,
+			Rand:         rand.New(rand.NewSource(seed)),
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid point template:", err.Error())
+			os.Exit(1)
+			return
+		}
+
+		if len(sets[i]) == 0 {
+			fmt.Fprintln(os.Stderr, "Point template produced no series:", t.Series)
+			os.Exit(1)
+			return
+		}
+
+		if err := validatePoint(sets[i][0], pc); err != nil {
+			fmt.Fprintln(os.Stderr, "Point template does not produce valid line protocol:", err.Error())
+			os.Exit(1)
+			return
+		}
+		if t.SharesSeriesN() && len(sets[i]) != t.SeriesN {
+			fmt.Fprintf(os.Stderr, "Point template %s generates %d series instead of %d, a multiple of the cardinality of its #N tags and lists of values\n", t.Series, len(sets[i]), t.SeriesN)
+		}
+		totalSeries += len(sets[i])
+	}
+
 	concurrency := pps / batchSize
 	// PPS takes precedence over batchSize.
 	// Adjust accordingly.
@@ -69,20 +218,39 @@ func insertRun(cmd *cobra.Command, args []string) {
 		concurrency = 1
 	}
 	if !quiet {
-		fmt.Printf("Using point template: %s %s <timestamp>\n", seriesKey, fieldStr)
+		for _, t := range templates {
+			if len(templates) == 1 && fromSample == "" {
+				fmt.Printf("Using point template: %s %s <timestamp>\n", t.Series, t.Fields)
+			} else {
+				fmt.Printf("Using point template: %s\n", t)
+			}
+		}
 		fmt.Printf("Using batch size of %d line(s)\n", batchSize)
-		fmt.Printf("Spreading writes across %d series\n", seriesN)
+		fmt.Printf("Spreading writes across %d series\n", totalSeries)
 		if fast {
 			fmt.Println("Output is unthrottled")
 		} else {
 			fmt.Printf("Throttling output to ~%d points/sec\n", pps)
 		}
 		fmt.Printf("Using %d concurrent writer(s)\n", concurrency)
+		fmt.Printf("Using seed %d\n", seed)
+		if churnRate > 0 {
+			fmt.Printf("Replacing %v%% of the series with new ones every %v\n", churnRate*100, churnInterval)
+		}
+		if backfill {
+			fmt.Printf("Backfilling from %v to %v with a point every %v\n", start.Format(time.RFC3339), end.Format(time.RFC3339), interval)
+		}
+		if lateRate+duplicateRate+futureRate > 0 {
+			fmt.Printf("Writing %v%% of the points late, %v%% as duplicates and %v%% in the future\n", lateRate*100, duplicateRate*100, futureRate*100)
+		}
+		if unit := pc.Duration(); unit > time.Nanosecond && (!backfill && (fast || unit > tick) || backfill && unit > interval) {
+			fmt.Printf("Writes are more frequent than the %v precision, timestamps will be advanced to avoid overwriting points\n", unit)
+		}
 
 		fmt.Printf("Running until ~%d points sent or until ~%v has elapsed\n", pointsN, runtime)
 	}
 
-	c := client()
+	c := client(pc)
 
 	if !kapacitorMode {
 		if err := c.Create(createCommand); err != nil {
@@ -93,11 +261,12 @@ func insertRun(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	pts := point.NewPoints(seriesKey, fieldStr, seriesN, lineprotocol.Nanosecond)
-
-	startSplit := 0
-	inc := int(seriesN) / int(concurrency)
-	endSplit := inc
+	writers, err := newWriters(sets, templates, int(concurrency))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid distribution:", err.Error())
+		os.Exit(1)
+		return
+	}
 
 	sink := newMultiSink(int(concurrency))
 	sink.AddSink(newErrorSink(int(concurrency)))
@@ -111,12 +280,17 @@ func insertRun(cmd *cobra.Command, args []string) {
 	var wg sync.WaitGroup
 	wg.Add(int(concurrency))
 
-	var totalWritten uint64
+	var totalWritten, churned uint64
+	var late, duplicates, future uint64
 
-	start := time.Now()
-	for i := uint64(0); i < concurrency; i++ {
+	if churnRate > 0 && !quiet {
+		go reportCardinality(totalSeries, &churned)
+	}
+
+	runStart := time.Now()
+	for _, w := range writers {
 
-		go func(startSplit, endSplit int) {
+		go func(w *writer) {
 			tick := time.Tick(tick)
 
 			if fast {
@@ -127,24 +301,45 @@ func insertRun(cmd *cobra.Command, args []string) {
 				BatchSize: batchSize,
 				MaxPoints: pointsN / concurrency, // divide by concurreny
 				GzipLevel: gzip,
-				Deadline:  time.Now().Add(runtime),
-				Tick:      tick,
-				Results:   sink.Chan(),
+				Precision: pc,
+				Selector:  w.selector,
+				Counts:    w.counts,
+
+				ChurnRate:     churnRate,
+				ChurnInterval: churnInterval,
+				Churned:       &churned,
+
+				Start:    start,
+				End:      end,
+				Interval: clockInterval,
+
+				Disorder: stress.Disorder{
+					LateRate:      lateRate,
+					LateOffset:    offset(newLateOffset(), w.rand),
+					DuplicateRate: duplicateRate,
+					FutureRate:    futureRate,
+					FutureOffset:  offset(newFutureOffset(), w.rand),
+					Late:          &late,
+					Duplicates:    &duplicates,
+					Future:        &future,
+				},
+				Rand: w.rand,
+
+				Deadline: time.Now().Add(runtime),
+				Tick:     tick,
+				Results:  sink.Chan(),
 			}
 
 			// Ignore duration from a single call to Write.
-			pointsWritten, _ := stress.Write(pts[startSplit:endSplit], c, cfg)
+			pointsWritten, _ := stress.Write(w.pts, c, cfg)
 			atomic.AddUint64(&totalWritten, pointsWritten)
 
 			wg.Done()
-		}(startSplit, endSplit)
-
-		startSplit = endSplit
-		endSplit += inc
+		}(w)
 	}
 
 	wg.Wait()
-	totalTime := time.Since(start)
+	totalTime := time.Since(runStart)
 	if err := c.Close(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error closing client: %v\n", err.Error())
 	}
@@ -156,6 +351,159 @@ func insertRun(cmd *cobra.Command, args []string) {
 	} else {
 		fmt.Println("Write Throughput:", throughput)
 		fmt.Println("Points Written:", totalWritten)
+		printPacketCounts(c)
+		if churnRate > 0 {
+			fmt.Println("Cumulative Series:", totalSeries+int(atomic.LoadUint64(&churned)))
+		}
+		if lateRate > 0 {
+			fmt.Println("Late Points:", atomic.LoadUint64(&late))
+		}
+		if duplicateRate > 0 {
+			fmt.Println("Duplicate Points:", atomic.LoadUint64(&duplicates))
+		}
+		if futureRate > 0 {
+			fmt.Println("Future Points:", atomic.LoadUint64(&future))
+		}
+		if len(templates) > 1 {
+			printMeasurementCounts(templates, writers)
+		}
+		if distribution != "uniform" {
+			printSeriesCounts(writers)
+		}
+	}
+}
+
+// printPacketCounts prints what was sent by the clients that have no
+// responses to report.
+func printPacketCounts(c write.Client) {
+	if pc, ok := c.(write.PacketCounter); ok {
+		fmt.Println("Packets Sent:", pc.Packets())
+		fmt.Println("Bytes Sent:", pc.Bytes())
+	}
+}
+
+// A writer holds the share of the points written by one goroutine.
+type writer struct {
+	pts      []lineprotocol.Point
+	selector stress.Selector
+	counts   []uint64
+
+	// rand is the source of all the randomness of the writer.
+	rand *rand.Rand
+
+	// set[i] is the index of the template that pts[i] was generated from.
+	set []int
+}
+
+// newWriters splits the points of each set between n writers, writer i
+// getting the points i, i+n, i+2n... so that the hottest points of the
+// --distribution are spread over the writers. Each writer interleaves its
+// share of the sets in proportion to the templates' weights, and picks the
+// series within a set from the --distribution over the whole set.
+func newWriters(sets [][]lineprotocol.Point, templates []point.Template, n int) ([]*writer, error) {
+	writers := make([]*writer, n)
+	for i := range writers {
+		w := &writer{rand: rand.New(rand.NewSource(seed + int64(i) + 1))}
+
+		groups := []stress.Group{}
+		for j, set := range sets {
+			if i >= len(set) {
+				continue
+			}
+
+			m := (len(set) - i + n - 1) / n
+			sel, err := stress.NewInterleavedSelector(distribution, m, i, n, len(set), w.rand)
+			if err != nil {
+				return nil, err
+			}
+
+			groups = append(groups, stress.Group{Offset: len(w.pts), Selector: sel, Weight: templates[j].Weight})
+			for k := i; k < len(set); k += n {
+				w.pts = append(w.pts, set[k])
+				w.set = append(w.set, j)
+			}
+		}
+
+		w.counts = make([]uint64, len(w.pts))
+		if len(groups) == 1 {
+			w.selector = groups[0].Selector
+		} else {
+			w.selector = stress.NewWeightedSelector(groups)
+		}
+		writers[i] = w
+	}
+
+	return writers, nil
+}
+
+// printMeasurementCounts prints the number of points written for each template.
+func printMeasurementCounts(templates []point.Template, writers []*writer) {
+	counts := make([]uint64, len(templates))
+	for _, w := range writers {
+		for i, n := range w.counts {
+			counts[w.set[i]] += n
+		}
+	}
+
+	fmt.Println("Points per measurement:")
+	for i, t := range templates {
+		fmt.Printf("  %s: %d\n", t.Measurement(), counts[i])
+	}
+}
+
+// reportCardinality periodically prints the number of series written so far,
+// which grows as series are churned.
+func reportCardinality(initial int, churned *uint64) {
+	const timeFormat = "[2006-01-02 15:04:05]"
+	for range time.Tick(churnInterval) {
+		fmt.Println(time.Now().Format(timeFormat), "Cumulative series:", initial+int(atomic.LoadUint64(churned)))
+	}
+}
+
+// printSeriesCounts prints how the writes were spread over the series.
+func printSeriesCounts(writers []*writer) {
+	pts := []lineprotocol.Point{}
+	counts := []uint64{}
+	for _, w := range writers {
+		pts = append(pts, w.pts...)
+		counts = append(counts, w.counts...)
+	}
+
+	order := make([]int, len(counts))
+	var total uint64
+	for i, n := range counts {
+		order[i] = i
+		total += n
+	}
+	sort.Slice(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+
+	fmt.Println("Writes per series:")
+	for _, pct := range []int{1, 10, 50} {
+		n := len(order) * pct / 100
+		if n < 1 {
+			n = 1
+		}
+
+		var sum uint64
+		for _, i := range order[:n] {
+			sum += counts[i]
+		}
+		fmt.Printf("  Hottest %d%% (%d series): %.1f%% of writes\n", pct, n, 100*float64(sum)/float64(total))
+	}
+
+	unwritten := 0
+	for _, n := range counts {
+		if n == 0 {
+			unwritten++
+		}
+	}
+	fmt.Printf("  Never written: %d series\n", unwritten)
+
+	if len(order) > 5 {
+		order = order[:5]
+	}
+	for _, i := range order {
+		fmt.Printf("  %d\t%s\n", counts[i], pts[i].Series())
 	}
 }
 
@@ -164,40 +512,166 @@ func init() {
 	insertCmd.Flags().StringVarP(&statsHost, "stats-host", "", "http://localhost:8086", "Address of InfluxDB instance where runtime statistics will be recorded")
 	insertCmd.Flags().StringVarP(&statsDB, "stats-db", "", "stress_stats", "Database that statistics will be written to")
 	insertCmd.Flags().BoolVarP(&recordStats, "stats", "", false, "Record runtime statistics")
-	insertCmd.Flags().StringVarP(&host, "host", "", "http://localhost:8086", "Address of InfluxDB instance")
-	insertCmd.Flags().StringVarP(&username, "user", "", "", "User to write data as")
-	insertCmd.Flags().StringVarP(&password, "pass", "", "", "Password for user")
-	insertCmd.Flags().StringVarP(&db, "db", "", "stress", "Database that will be written to")
-	insertCmd.Flags().StringVarP(&rp, "rp", "", "", "Retention Policy that will be written to")
-	insertCmd.Flags().StringVarP(&precision, "precision", "p", "n", "Resolution of data being written")
-	insertCmd.Flags().StringVarP(&consistency, "consistency", "c", "one", "Write consistency (only applicable to clusters)")
-	insertCmd.Flags().IntVarP(&seriesN, "series", "s", 100000, "number of series that will be written")
+	addClientFlags(insertCmd.Flags())
+	insertCmd.Flags().StringVarP(&precision, "precision", "p", "n", "Resolution of data being written (n, u, ms, s, m or h)")
+	insertCmd.Flags().IntVarP(&seriesN, "series", "s", 100000, "number of series that will be written, shared by the tags without a #N cardinality")
+	insertCmd.Flags().IntVar(&stringLength, "string-length", 0, "If non-zero, string fields take random values of this length")
+	insertCmd.Flags().IntVar(&stringPool, "string-pool", 0, "If non-zero, number of distinct values each string field takes, otherwise every write has a new value when --string-length is set")
+	insertCmd.Flags().StringVar(&fromSample, "from-sample", "", "Add the measurements of a line protocol file, shaped like its points")
+	insertCmd.Flags().StringArrayVar(&measurements, "measurement", nil, "Add a measurement written as 'SERIES [FIELDS [series=N] [weight=W]]', may be repeated")
 	insertCmd.Flags().Uint64VarP(&pointsN, "points", "n", math.MaxUint64, "number of points that will be written")
 	insertCmd.Flags().Uint64VarP(&batchSize, "batch-size", "b", 10000, "number of points in a batch")
 	insertCmd.Flags().Uint64VarP(&pps, "pps", "", 200000, "Points Per Second")
 	insertCmd.Flags().DurationVarP(&runtime, "runtime", "r", time.Duration(math.MaxInt64), "Total time that the test will run")
 	insertCmd.Flags().DurationVarP(&tick, "tick", "", time.Second, "Amount of time between request")
+	insertCmd.Flags().StringVar(&distribution, "distribution", "uniform", "How writes are spread over the series: uniform, zipf(s) or hot(x,y) for x% of writes to y% of series")
+	insertCmd.Flags().Float64Var(&churnRate, "churn-rate", 0, "Fraction of the series replaced with new ones every --churn-interval, e.g. 0.01 for 1%")
+	insertCmd.Flags().DurationVar(&churnInterval, "churn-interval", time.Minute, "How often series are churned")
+	insertCmd.Flags().StringVar(&startTime, "start-time", "", "If set, backfill from this time, as RFC3339 or a duration before now such as -720h, instead of using the current time")
+	insertCmd.Flags().StringVar(&endTime, "end-time", "", "Time the backfill stops at, as RFC3339 or a duration before now (default now)")
+	insertCmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "Time between the points of each series when backfilling")
+	insertCmd.Flags().Float64Var(&lateRate, "late-rate", 0, "Fraction of the points written late, shifted back by --late-offset")
+	insertCmd.Flags().StringVar(&lateOffset, "late-offset", "uniform(0,3600)", "Generator of the seconds late points are shifted back by")
+	insertCmd.Flags().Float64Var(&duplicateRate, "duplicate-rate", 0, "Fraction of the points written again with the previous timestamp of their series and new values")
+	insertCmd.Flags().Float64Var(&futureRate, "future-rate", 0, "Fraction of the points dated in the future, shifted forward by --future-offset")
+	insertCmd.Flags().StringVar(&futureOffset, "future-offset", "uniform(0,3600)", "Generator of the seconds future points are shifted forward by")
+	insertCmd.Flags().Int64Var(&seed, "seed", 0, "Seed of all the randomness of the run, so runs with the same seed and --start-time write the same points. If 0, a seed is picked and printed")
 	insertCmd.Flags().BoolVarP(&fast, "fast", "f", false, "Run as fast as possible")
 	insertCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print the write throughput")
-	insertCmd.Flags().StringVar(&createCommand, "create", "", "Use a custom create database command")
-	insertCmd.Flags().BoolVarP(&kapacitorMode, "kapacitor", "k", false, "Use Kapacitor mode, namely do not try to run any queries.")
-	insertCmd.Flags().IntVar(&gzip, "gzip", 0, "If non-zero, gzip write bodies with given compression level. 1=best speed, 9=best compression, -1=gzip default.")
-	insertCmd.Flags().StringVar(&dump, "dump", "", "Dump to given file instead of writing over HTTP")
-	insertCmd.Flags().BoolVarP(&strict, "strict", "", false, "Strict mode will exit as soon as an error or unexpected status is encountered")
-	insertCmd.Flags().BoolVarP(&tlsSkipVerify, "tls-skip-verify", "", false, "Skip verify in for TLS")
 }
 
-func client() write.Client {
+// addClientFlags adds the flags of the host written to and of the protocol
+// written, shared by the commands that write.
+func addClientFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&host, "host", "", "http://localhost:8086", "Address of InfluxDB instance, unix:///path/to/influxdb.sock for its unix socket, or udp://host:port or tcp://host:port of a socket listener")
+	flags.IntVar(&udpPayload, "udp-payload", write.DefaultUDPPayload, "Maximum size in bytes of the packets sent to a udp:// host, batches are split on line boundaries to fit")
+	flags.StringVar(&format, "format", "line", "Protocol written: line for line protocol, graphite for the Graphite plaintext protocol to a tcp:// or udp:// host, opentsdb for OpenTSDB put commands to a tcp:// host or JSON to the /api/put of an http:// host, or prometheus for remote write requests to the --host URL, at /api/v1/write if it has no path")
+	flags.StringVar(&graphiteTemplate, "graphite-template", write.DefaultGraphiteTemplate, "Metric path of the fields with the graphite format, from measurement, field, tags for the remaining tag values and the names of tags")
+	flags.StringVarP(&username, "user", "", "", "User to write data as")
+	flags.StringVarP(&password, "pass", "", "", "Password for user")
+	flags.StringVar(&api, "api", "v1", "Write API to use, v1 for /write, v2 for the /api/v2/write of InfluxDB 2.x or v3 for the /api/v3/write_lp of InfluxDB 3")
+	flags.StringVar(&org, "org", "", "Organization of the bucket written to with the v2 API")
+	flags.StringVar(&bucket, "bucket", "", "Bucket written to with the v2 API, created if needed (default the --db)")
+	flags.StringVar(&token, "token", "", "Token to authenticate with the v2 or v3 API (default $INFLUX_TOKEN)")
+	flags.BoolVar(&acceptPartial, "accept-partial", true, "Write the valid lines of a batch with invalid lines, with the v3 API")
+	flags.BoolVar(&noSync, "no-sync", false, "Acknowledge writes before they are persisted, with the v3 API")
+	flags.StringVarP(&db, "db", "", "stress", "Database that will be written to")
+	flags.StringVarP(&rp, "rp", "", "", "Retention Policy that will be written to")
+	flags.StringVarP(&consistency, "consistency", "c", "one", "Write consistency (only applicable to clusters)")
+	flags.StringVar(&createCommand, "create", "", "Use a custom create database command")
+	flags.BoolVarP(&kapacitorMode, "kapacitor", "k", false, "Use Kapacitor mode, namely do not try to run any queries.")
+	flags.IntVar(&gzip, "gzip", 0, "If non-zero, gzip write bodies with given compression level. 1=best speed, 9=best compression, -1=gzip default.")
+	flags.StringVar(&dump, "dump", "", "Dump to given file instead of writing over HTTP")
+	flags.BoolVarP(&strict, "strict", "", false, "Strict mode will exit as soon as an error or unexpected status is encountered")
+	flags.BoolVarP(&tlsSkipVerify, "tls-skip-verify", "", false, "Skip verify in for TLS")
+}
+
+// offset returns a function giving the values of g drawn from r as durations
+// in seconds, with negative values becoming 0.
+func offset(g point.Generator, r *rand.Rand) func() time.Duration {
+	return func() time.Duration {
+		v := g.Next(r)
+		if v < 0 {
+			return 0
+		}
+		return time.Duration(v * float64(time.Second))
+	}
+}
+
+// inferTemplates returns the templates of the measurements in the line
+// protocol file at path.
+func inferTemplates(path string) ([]point.Template, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	templates, err := point.InferTemplates(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("no points in %s", path)
+	}
+
+	return templates, nil
+}
+
+// parseTime parses s as an RFC3339 time, or as a duration relative to now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d), nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// validatePoint checks that pt is written as valid line protocol, by
+// parsing it back and comparing the result.
+func validatePoint(pt lineprotocol.Point, pc lineprotocol.Precision) error {
+	pt.SetTime(time.Now())
+
+	buf := bytes.NewBuffer(nil)
+	if err := lineprotocol.WritePoint(buf, pt); err != nil {
+		return err
+	}
+	line := buf.String()
+
+	parsed, err := lineprotocol.ParsePoints(buf.Bytes(), pc)
+	if err != nil {
+		return err
+	}
+
+	buf.Reset()
+	if err := lineprotocol.WritePoint(buf, parsed[0]); err != nil {
+		return err
+	}
+
+	if buf.String() != line {
+		return fmt.Errorf("%q was parsed as %q", line, buf.String())
+	}
+
+	return nil
+}
+
+func client(pc lineprotocol.Precision) write.Client {
 	cfg := write.ClientConfig{
 		BaseURL:         host,
 		Database:        db,
 		RetentionPolicy: rp,
 		User:            username,
 		Pass:            password,
-		Precision:       precision,
+		Precision:       pc.String(),
 		Consistency:     consistency,
 		TLSSkipVerify:   tlsSkipVerify,
 		Gzip:            gzip != 0,
+		API:             api,
+		Org:             org,
+		Bucket:          bucket,
+		Token:           token,
+		AcceptPartial:   acceptPartial,
+		NoSync:          noSync,
+		UDPPayload:      udpPayload,
+
+		GraphiteTemplate: graphiteTemplate,
+	}
+	if cfg.Bucket == "" {
+		cfg.Bucket = db
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("INFLUX_TOKEN")
+	}
+
+	if api != "v1" && api != "v2" && api != "v3" {
+		fmt.Fprintln(os.Stderr, "Unknown API:", api)
+		os.Exit(1)
+	}
+
+	if format != "line" && format != "graphite" && format != "opentsdb" && format != "prometheus" {
+		fmt.Fprintln(os.Stderr, "Unknown format:", format)
+		os.Exit(1)
 	}
 
 	if dump != "" {
@@ -210,6 +684,66 @@ func client() write.Client {
 
 		return c
 	}
+
+	// The other formats are encoded from the points by their clients, and
+	// converted from the line protocol replayed.
+	if format != "line" {
+		if gzip != 0 {
+			fmt.Fprintln(os.Stderr, "Gzip is not supported with the", format, "format")
+			os.Exit(1)
+		}
+
+		newClient := write.NewGraphiteClient
+		switch format {
+		case "opentsdb":
+			newClient = write.NewOpenTSDBClient
+		case "prometheus":
+			newClient = write.NewPrometheusClient
+		}
+		c, err := newClient(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %s client: %v\n", format, err)
+			os.Exit(1)
+		}
+		return c
+	}
+
+	// Line protocol is streamed as is to the socket listeners.
+	if u, err := url.Parse(host); err == nil && (u.Scheme == "udp" || u.Scheme == "tcp") {
+		transport := strings.ToUpper(u.Scheme)
+		if gzip != 0 {
+			fmt.Fprintln(os.Stderr, "Gzip is not supported over", transport)
+			os.Exit(1)
+		}
+
+		newClient := write.NewUDPClient
+		if u.Scheme == "tcp" {
+			newClient = write.NewTCPClient
+		}
+		c, err := newClient(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %s client: %v\n", transport, err)
+			os.Exit(1)
+		}
+		return c
+	}
+
+	switch api {
+	case "v2":
+		c, err := write.NewV2Client(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid v2 client:", err)
+			os.Exit(1)
+		}
+		return c
+	case "v3":
+		c, err := write.NewV3Client(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid v3 client:", err)
+			os.Exit(1)
+		}
+		return c
+	}
 	return write.NewClient(cfg)
 }
 
@@ -256,12 +790,14 @@ func (s *errorSink) checkErrors() {
 			continue
 		}
 
-		if r.StatusCode != 204 {
+		// InfluxDB answers 204, other backends such as remote write
+		// storages may answer 200.
+		if r.StatusCode/100 != 2 {
 			fmt.Fprintln(os.Stderr, time.Now().Format(timeFormat), "Unexpected write: status", r.StatusCode, ", body:", r.Body)
 		}
 
 		// If we're running in strict mode then we give up at the first error.
-		if strict && (r.Err != nil || r.StatusCode != 204) {
+		if strict && (r.Err != nil || r.StatusCode/100 != 2) {
 			os.Exit(1)
 		}
 	}
@@ -277,11 +813,13 @@ type multiSink struct {
 	sinks []Sink
 
 	open bool
+	done chan struct{}
 }
 
 func newMultiSink(nWriters int) *multiSink {
 	return &multiSink{
-		Ch: make(chan stress.WriteResult, 8*nWriters),
+		Ch:   make(chan stress.WriteResult, 8*nWriters),
+		done: make(chan struct{}),
 	}
 }
 
@@ -309,10 +847,15 @@ func (s *multiSink) run() {
 			}
 		}
 	}
+	close(s.done)
 }
 
+// Close must only be called once nothing more is sent on Ch. It waits for the
+// results already sent to be passed on before closing the sinks.
 func (s *multiSink) Close() {
 	s.open = false
+	close(s.Ch)
+	<-s.done
 	for _, sink := range s.sinks {
 		sink.Close()
 	}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+	"github.com/influxdata/influx-stress/point"
+)
+
+// writeCounts returns the number of writes to each of the series of a
+// template split between writersN writers, when every writer picks picks points.
+func writeCounts(t *testing.T, dist string, series, writersN, picks int) []int {
+	defer func(d string) { distribution = d }(distribution)
+	distribution = dist
+
+	tmpl := point.Template{Series: "cpu,host=#" + strconv.Itoa(series), Fields: "n=0i", SeriesN: series, Weight: 1}
+	pts, err := tmpl.NewPoints(point.Config{Precision: lineprotocol.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writers, err := newWriters([][]lineprotocol.Point{pts}, []point.Template{tmpl}, writersN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := map[lineprotocol.Point]int{}
+	for i, pt := range pts {
+		index[pt] = i
+	}
+
+	counts := make([]int, series)
+	for _, w := range writers {
+		for i := 0; i < picks; i++ {
+			counts[index[w.pts[w.selector.Next()]]]++
+		}
+	}
+
+	return counts
+}
+
+func TestNewWriters_Hot(t *testing.T) {
+	counts := writeCounts(t, "hot(80,20)", 100, 4, 100000)
+
+	hot, total := 0, 0
+	for i, c := range counts {
+		if i < 20 {
+			hot += c
+		}
+		total += c
+	}
+
+	if share := float64(hot) / float64(total); share < 0.78 || share > 0.82 {
+		t.Errorf("Wrong share of writes to the hottest 20%% of the series. got %v, exp %v", share, 0.8)
+	}
+}
+
+func TestNewWriters_Zipf(t *testing.T) {
+	counts := writeCounts(t, "zipf(2)", 100, 4, 100000)
+
+	// The hottest series of the writers are not equally hot, and the series
+	// of each writer follow their rank. A series only gets more writes than
+	// a series of lower rank of another writer when that one holds more of
+	// the distribution than the share of writes of its writer.
+	for i := 1; i < 4; i++ {
+		if counts[i] >= counts[i-1] {
+			t.Fatalf("The hottest series of the writers are not ranked: %v", counts[:4])
+		}
+	}
+	for i := 4; i < 40; i++ {
+		if counts[i] > counts[i-4] {
+			t.Fatalf("The series of writer %d are not ranked: %v", i%4, counts[:40])
+		}
+	}
+}
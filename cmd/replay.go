@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	compress "compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+	"github.com/influxdata/influx-stress/stress"
+	"github.com/influxdata/influx-stress/write"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayPrecision string
+	replayBatchSize uint64
+	replayPPS       uint64
+	rewriteTime     bool
+)
+
+// defaultReplayBatchSize is the batch size of files without batch headers.
+const defaultReplayBatchSize = 10000
+
+var replayCmd = &cobra.Command{
+	Use:   "replay FILE",
+	Short: "Replay line protocol from a file, such as a --dump, into InfluxDB",
+	Long:  "",
+	Run:   replayRun,
+}
+
+func replayRun(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Expected the file to replay as the only argument")
+		cmd.Usage()
+		os.Exit(1)
+		return
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening file:", err)
+		os.Exit(1)
+		return
+	}
+	defer f.Close()
+
+	d, err := write.NewDumpReader(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading file:", err)
+		os.Exit(1)
+		return
+	}
+
+	// The precision of a dump is in the write URL of its header.
+	p := replayPrecision
+	if p == "" && d.URL != "" {
+		if u, err := url.Parse(d.URL); err == nil {
+			p = u.Query().Get("precision")
+		}
+	}
+	pc, err := lineprotocol.ParsePrecision(p)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid precision:", err.Error())
+		os.Exit(1)
+		return
+	}
+
+	// Without batch headers to preserve, the lines are sent in batches
+	// of the default size.
+	batchSize := replayBatchSize
+	if batchSize == 0 && d.URL == "" {
+		batchSize = defaultReplayBatchSize
+	}
+
+	if !quiet {
+		fmt.Printf("Replaying %s with precision %s\n", args[0], pc)
+		if batchSize == 0 {
+			fmt.Println("Keeping the batches of the dump")
+		} else {
+			fmt.Printf("Using batch size of %d line(s)\n", batchSize)
+		}
+		if replayPPS > 0 {
+			fmt.Printf("Throttling output to ~%d points/sec\n", replayPPS)
+		}
+		if rewriteTime {
+			fmt.Println("Shifting timestamps so the first point is written at the current time")
+		}
+	}
+
+	c := client(pc)
+
+	if !kapacitorMode {
+		if err := c.Create(createCommand); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to create database:", err.Error())
+			fmt.Fprintln(os.Stderr, "Aborting.")
+			os.Exit(1)
+			return
+		}
+	}
+
+	sink := newErrorSink(1)
+
+	var (
+		buf     = bytes.NewBuffer(nil)
+		body    = bytes.NewBuffer(nil)
+		lines   uint64
+		batches uint64
+		written uint64
+		// offset is added to the timestamps when rewriting them.
+		offset  time.Duration
+		shifted bool
+	)
+
+	start := time.Now()
+	send := func() {
+		b := buf.Bytes()
+		if gzip != 0 {
+			body.Reset()
+			gzw, err := compress.NewWriterLevel(body, gzip)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Invalid gzip level:", err)
+				os.Exit(1)
+			}
+			gzw.Write(b)
+			gzw.Close()
+			b = body.Bytes()
+		}
+
+		lat, status, respBody, err := c.Send(b)
+		sink.Chan() <- stress.WriteResult{LatNs: lat, StatusCode: status, Body: respBody, Err: err, Timestamp: time.Now().UnixNano()}
+
+		batches++
+		written += lines
+		lines = 0
+		buf.Reset()
+
+		if replayPPS > 0 {
+			time.Sleep(time.Until(start.Add(time.Duration(float64(written) / float64(replayPPS) * float64(time.Second)))))
+		}
+	}
+
+	for n := 1; ; n++ {
+		line, first, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading file:", err)
+			os.Exit(1)
+			return
+		}
+
+		if lines > 0 && (batchSize == 0 && first || batchSize > 0 && lines == batchSize) {
+			send()
+		}
+
+		if !rewriteTime {
+			buf.Write(line)
+			buf.WriteByte('\n')
+			lines++
+			continue
+		}
+
+		pt, err := lineprotocol.ParseLine(line, pc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid point %d: %v\n", n, err)
+			os.Exit(1)
+			return
+		}
+
+		// Lines without a timestamp are written as is, to get the time
+		// they are written at.
+		if !pt.HasTime() {
+			buf.Write(line)
+			buf.WriteByte('\n')
+			lines++
+			continue
+		}
+
+		t := pt.Time().Time()
+		if !shifted {
+			offset, shifted = time.Since(t), true
+		}
+		pt.SetTime(t.Add(offset))
+		lineprotocol.WritePoint(buf, pt)
+		lines++
+	}
+
+	if lines > 0 {
+		send()
+	}
+
+	totalTime := time.Since(start)
+	if err := c.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error closing client: %v\n", err.Error())
+	}
+
+	sink.Close()
+	throughput := int(float64(written) / totalTime.Seconds())
+	if quiet {
+		fmt.Println(throughput)
+	} else {
+		fmt.Println("Write Throughput:", throughput)
+		fmt.Println("Points Written:", written)
+		printPacketCounts(c)
+		fmt.Println("Batches Written:", batches)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(replayCmd)
+	addClientFlags(replayCmd.Flags())
+	replayCmd.Flags().StringVarP(&replayPrecision, "precision", "p", "", "Resolution of the timestamps in the file (n, u, ms, s, m or h), by default the precision of the dump or n")
+	replayCmd.Flags().Uint64VarP(&replayBatchSize, "batch-size", "b", 0, "If non-zero, send batches of this many points instead of the batches of the dump")
+	replayCmd.Flags().Uint64VarP(&replayPPS, "pps", "", 0, "If non-zero, Points Per Second, otherwise the file is replayed as fast as possible")
+	replayCmd.Flags().BoolVar(&rewriteTime, "rewrite-time", false, "Shift the timestamps so the first point is written at the current time")
+	replayCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print the write throughput")
+}
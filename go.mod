@@ -4,7 +4,8 @@ go 1.13
 
 require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/klauspost/compress v1.15.0
 	github.com/spf13/cobra v0.0.0-20160830174925-9c28e4bbd74e
-	github.com/spf13/pflag v0.0.0-20160911194638-7b17cc4658ef // indirect
+	github.com/spf13/pflag v0.0.0-20160911194638-7b17cc4658ef
 	github.com/valyala/fasthttp v1.34.0
 )
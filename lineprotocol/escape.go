@@ -0,0 +1,79 @@
+package lineprotocol
+
+import "bytes"
+
+const (
+	measurementSpecials = ", "
+	tagSpecials         = ",= "
+)
+
+// EscapeMeasurement escapes the commas and spaces in a measurement name.
+func EscapeMeasurement(b []byte) []byte {
+	return escape(b, measurementSpecials)
+}
+
+// EscapeTag escapes the commas, equals signs and spaces in a tag key or value.
+func EscapeTag(b []byte) []byte {
+	return escape(b, tagSpecials)
+}
+
+// EscapeFieldKey escapes the commas, equals signs and spaces in a field key.
+func EscapeFieldKey(b []byte) []byte {
+	return escape(b, tagSpecials)
+}
+
+// EscapeString escapes the double quotes and backslashes in a string field
+// value. The surrounding quotes are not added.
+func EscapeString(b []byte) []byte {
+	return escape(b, `"\`)
+}
+
+// Unescape removes the backslashes escaping commas, equals signs, spaces
+// and double quotes from a measurement name, tag key, tag value or field key.
+// Other backslashes are kept, as InfluxDB does.
+func Unescape(b []byte) []byte {
+	if bytes.IndexByte(b, '\\') == -1 {
+		return b
+	}
+
+	u := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\\' && i+1 < len(b) && bytes.IndexByte([]byte(`,= "`), b[i+1]) != -1 {
+			i++
+		}
+		u = append(u, b[i])
+	}
+
+	return u
+}
+
+// escape returns b with a backslash before each of the special characters.
+// If there are no special characters b itself is returned.
+func escape(b []byte, specials string) []byte {
+	if bytes.IndexAny(b, specials) == -1 {
+		return b
+	}
+
+	e := make([]byte, 0, len(b)+4)
+	for _, c := range b {
+		if bytes.IndexByte([]byte(specials), c) != -1 {
+			e = append(e, '\\')
+		}
+		e = append(e, c)
+	}
+
+	return e
+}
+
+// appendEscapedString appends s to buf with the double quotes and
+// backslashes escaped, as required inside a string field value.
+func appendEscapedString(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, s[i])
+	}
+
+	return buf
+}
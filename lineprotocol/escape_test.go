@@ -0,0 +1,43 @@
+package lineprotocol_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		escape func([]byte) []byte
+		in     string
+		exp    string
+	}{
+		{lineprotocol.EscapeMeasurement, "cpu", "cpu"},
+		{lineprotocol.EscapeMeasurement, "my cpu,a=b", `my\ cpu\,a=b`},
+		{lineprotocol.EscapeTag, "/var/lib docker", `/var/lib\ docker`},
+		{lineprotocol.EscapeTag, "a,b=c", `a\,b\=c`},
+		{lineprotocol.EscapeFieldKey, "used percent=x", `used\ percent\=x`},
+		{lineprotocol.EscapeString, `say "hi" \o/`, `say \"hi\" \\o/`},
+	}
+
+	for _, test := range tests {
+		if got := string(test.escape([]byte(test.in))); got != test.exp {
+			t.Errorf("Wrong escaping of %q. got %v, exp %v", test.in, got, test.exp)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := map[string]string{
+		"cpu":                 "cpu",
+		`/var/lib\ docker`:    "/var/lib docker",
+		`a\,b\=c`:             "a,b=c",
+		`C:\Program\ Files\x`: `C:\Program Files\x`,
+	}
+
+	for in, exp := range tests {
+		if got := string(lineprotocol.Unescape([]byte(in))); got != exp {
+			t.Errorf("Wrong unescaping of %q. got %v, exp %v", in, got, exp)
+		}
+	}
+}
@@ -12,14 +12,17 @@ var comma = byte(',')
 // Field is an aliased io.WriterTo.
 type Field io.WriterTo
 
-// Verify that *Int and *Float implement Field.
+// Verify that *Int, *Uint, *Float, *String and *Bool implement Field.
 var (
 	_ Field = &Int{}
+	_ Field = &Uint{}
 	_ Field = &Float{}
+	_ Field = &String{}
+	_ Field = &Bool{}
 )
 
 // Int implements the Field interface. Key is the line protocol
-// field key as a byte slice. Value is the integer key value for
+// field key as a byte slice, unescaped. Value is the integer key value for
 // the field.
 //
 // Not that Value occurs before Key in the Int struct. The reason for this
@@ -36,7 +39,7 @@ type Int struct {
 // For example if i.Key = []byte("value") and i.Value = 1
 // then `value=1i` is written.
 func (i *Int) WriteTo(w io.Writer) (int64, error) {
-	n, err := w.Write(i.Key)
+	n, err := w.Write(EscapeFieldKey(i.Key))
 	if err != nil {
 		return int64(n), err
 	}
@@ -53,8 +56,39 @@ func (i *Int) WriteTo(w io.Writer) (int64, error) {
 	return int64(n + m), err
 }
 
+// Uint implements the Field interface. Key is the line protocol
+// field key as a byte slice, unescaped. Value is the unsigned integer value for
+// the field.
+//
+// Value occurs before Key for the same alignment reasons as in Int.
+type Uint struct {
+	Value uint64
+	Key   []byte
+}
+
+// WriteTo writes the field key value pair to an io.Writer.
+// For example if u.Key = []byte("value") and u.Value = 1
+// then `value=1u` is written.
+func (u *Uint) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(EscapeFieldKey(u.Key))
+	if err != nil {
+		return int64(n), err
+	}
+
+	// Max uint64 fits in 20 base-10 digits,
+	// plus 1 for the leading =, plus 1 for the trailing u required for uints.
+	buf := make([]byte, 0, 22)
+	buf = append(buf, equalSign)
+	buf = strconv.AppendUint(buf, atomic.LoadUint64(&u.Value), 10)
+	buf = append(buf, 'u')
+
+	m, err := w.Write(buf)
+
+	return int64(n + m), err
+}
+
 // Float implements the Field interface. Key is the line protocol
-// field key as a byte slice. Value is the float key value for
+// field key as a byte slice, unescaped. Value is the float key value for
 // the field.
 type Float struct {
 	Key   []byte
@@ -65,7 +99,7 @@ type Float struct {
 // For example if i.Key = []byte("value") and i.Value = 1
 // then `value=1` is written.
 func (f *Float) WriteTo(w io.Writer) (int64, error) {
-	n, err := w.Write(f.Key)
+	n, err := w.Write(EscapeFieldKey(f.Key))
 	if err != nil {
 		return int64(n), err
 	}
@@ -80,3 +114,56 @@ func (f *Float) WriteTo(w io.Writer) (int64, error) {
 
 	return int64(n + m), err
 }
+
+// String implements the Field interface. Key is the line protocol
+// field key as a byte slice, unescaped. Value is the string value for the field,
+// which is quoted and escaped when written.
+type String struct {
+	Key   []byte
+	Value string
+}
+
+// WriteTo writes the field key value pair to an io.Writer
+// For example if s.Key = []byte("value") and s.Value = `say "hi"`
+// then `value="say \"hi\""` is written.
+func (s *String) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(EscapeFieldKey(s.Key))
+	if err != nil {
+		return int64(n), err
+	}
+
+	// Room for the =, the quotes and a few escaped characters.
+	buf := make([]byte, 0, len(s.Value)+8)
+	buf = append(buf, equalSign, '"')
+	buf = appendEscapedString(buf, s.Value)
+	buf = append(buf, '"')
+
+	m, err := w.Write(buf)
+
+	return int64(n + m), err
+}
+
+// Bool implements the Field interface. Key is the line protocol
+// field key as a byte slice, unescaped. Value is the boolean value for the field.
+type Bool struct {
+	Key   []byte
+	Value bool
+}
+
+// WriteTo writes the field key value pair to an io.Writer
+// For example if b.Key = []byte("value") and b.Value = true
+// then `value=true` is written.
+func (b *Bool) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(EscapeFieldKey(b.Key))
+	if err != nil {
+		return int64(n), err
+	}
+
+	buf := make([]byte, 0, 6)
+	buf = append(buf, equalSign)
+	buf = strconv.AppendBool(buf, b.Value)
+
+	m, err := w.Write(buf)
+
+	return int64(n + m), err
+}
@@ -50,3 +50,91 @@ func TestFloat_WriteTo(t *testing.T) {
 		return
 	}
 }
+
+func TestString_WriteTo(t *testing.T) {
+	i := &lineprotocol.String{
+		Key:   []byte("a"),
+		Value: `say "hi" \o/`,
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	if _, err := i.WriteTo(buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	exp := `a="say \"hi\" \\o/"`
+	got := string(buf.Bytes())
+
+	if got != exp {
+		t.Errorf("Wrong field data written. got %v, exp %v", got, exp)
+		return
+	}
+}
+
+func TestUint_WriteTo(t *testing.T) {
+	i := &lineprotocol.Uint{
+		Key:   []byte("a"),
+		Value: uint64(18446744073709551615),
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	if _, err := i.WriteTo(buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	exp := "a=18446744073709551615u"
+	got := string(buf.Bytes())
+
+	if got != exp {
+		t.Errorf("Wrong field data written. got %v, exp %v", got, exp)
+		return
+	}
+}
+
+func TestBool_WriteTo(t *testing.T) {
+	i := &lineprotocol.Bool{
+		Key:   []byte("a"),
+		Value: true,
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	if _, err := i.WriteTo(buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	exp := "a=true"
+	got := string(buf.Bytes())
+
+	if got != exp {
+		t.Errorf("Wrong field data written. got %v, exp %v", got, exp)
+		return
+	}
+}
+
+func TestField_WriteTo_EscapedKey(t *testing.T) {
+	i := &lineprotocol.Int{
+		Key:   []byte("used percent"),
+		Value: int64(1),
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	if _, err := i.WriteTo(buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	exp := `used\ percent=1i`
+	got := string(buf.Bytes())
+
+	if got != exp {
+		t.Errorf("Wrong field data written. got %v, exp %v", got, exp)
+		return
+	}
+}
@@ -0,0 +1,343 @@
+package lineprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Tag is a tag key and value of a parsed point, both unescaped.
+type Tag struct {
+	Key   []byte
+	Value []byte
+}
+
+// ParsedPoint is a point decoded from a line of line protocol. It implements
+// the Point interface, so it can be written back out with WritePoint.
+type ParsedPoint struct {
+	// Measurement is the unescaped measurement name.
+	Measurement []byte
+	Tags        []Tag
+
+	// series is the escaped series key, exactly as it was in the line.
+	series []byte
+	fields []Field
+	time   *Timestamp
+
+	// timed is whether the line had a timestamp.
+	timed bool
+}
+
+// Verify that *ParsedPoint implements Point.
+var _ Point = &ParsedPoint{}
+
+// Series returns the series key of the point, as it was written in the line.
+func (p *ParsedPoint) Series() []byte {
+	return p.series
+}
+
+// Fields returns the fields of the point. Each is one of *Int, *Uint, *Float,
+// *String or *Bool, with the key unescaped.
+func (p *ParsedPoint) Fields() []Field {
+	return p.fields
+}
+
+// Time returns the timestamp of the point. If the line had no timestamp
+// it is the time the line was parsed.
+func (p *ParsedPoint) Time() *Timestamp {
+	return p.time
+}
+
+// HasTime returns whether the line of the point had a timestamp.
+func (p *ParsedPoint) HasTime() bool {
+	return p.timed
+}
+
+// SetTime sets t to be the timestamp of the point.
+func (p *ParsedPoint) SetTime(t time.Time) {
+	p.time.SetTime(&t)
+}
+
+// Update does nothing, the values of a parsed point do not change.
+func (p *ParsedPoint) Update() {}
+
+// ParseError is the error returned for a line that is not valid line protocol.
+type ParseError struct {
+	// Line is the 1-based line number, or 0 if the line was parsed on its own.
+	Line int
+	Text string
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %q", e.Msg, e.Text)
+	}
+	return fmt.Sprintf("line %d: %s: %q", e.Line, e.Msg, e.Text)
+}
+
+// Parser reads points from line protocol one line at a time.
+type Parser struct {
+	r         *bufio.Reader
+	precision Precision
+	line      int
+}
+
+// NewParser returns a Parser reading from r, with timestamps in the precision p.
+func NewParser(r io.Reader, p Precision) *Parser {
+	return &Parser{
+		r:         bufio.NewReader(r),
+		precision: p,
+	}
+}
+
+// Next returns the next point, skipping blank lines and comments, or io.EOF
+// when there are no more lines. A *ParseError is returned for an invalid line,
+// and Next may be called again to continue with the line after it.
+func (p *Parser) Next() (*ParsedPoint, error) {
+	for {
+		line, err := p.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		p.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		pt, perr := ParseLine(line, p.precision)
+		if perr != nil {
+			perr.(*ParseError).Line = p.line
+			return nil, perr
+		}
+
+		return pt, nil
+	}
+}
+
+// ParsePoints parses every line of b. It stops at the first invalid line and
+// returns the points parsed so far along with a *ParseError.
+func ParsePoints(b []byte, precision Precision) ([]*ParsedPoint, error) {
+	pts := []*ParsedPoint{}
+
+	p := NewParser(bytes.NewReader(b), precision)
+	for {
+		pt, err := p.Next()
+		if err == io.EOF {
+			return pts, nil
+		} else if err != nil {
+			return pts, err
+		}
+		pts = append(pts, pt)
+	}
+}
+
+// ParseLine parses a single line of line protocol, without the trailing
+// newline. Timestamps are read in the precision p. The error, if any,
+// is a *ParseError.
+func ParseLine(line []byte, p Precision) (*ParsedPoint, error) {
+	pt, err := parseLine(line, p)
+	if err != nil {
+		return nil, &ParseError{Text: string(line), Msg: err.Error()}
+	}
+
+	return pt, nil
+}
+
+func parseLine(line []byte, p Precision) (*ParsedPoint, error) {
+	pt := &ParsedPoint{time: NewTimestamp(p)}
+
+	// The series key ends at the first unescaped space.
+	i := scanTo(line, 0, ' ', false)
+	if i == len(line) {
+		return nil, errors.New("missing fields")
+	}
+	pt.series = line[:i]
+	if err := pt.parseSeries(); err != nil {
+		return nil, err
+	}
+
+	// The field set ends at the next unescaped space outside a string.
+	start := skipSpaces(line, i)
+	end := scanTo(line, start, ' ', true)
+	if err := pt.parseFields(line[start:end]); err != nil {
+		return nil, err
+	}
+
+	ts := bytes.TrimSpace(line[end:])
+	if len(ts) == 0 {
+		pt.SetTime(time.Now())
+		return pt, nil
+	}
+
+	n, err := strconv.ParseInt(string(ts), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %s", ts)
+	}
+
+	unit := int64(p.Duration())
+	if n > math.MaxInt64/unit || n < math.MinInt64/unit {
+		return nil, fmt.Errorf("timestamp %s out of range", ts)
+	}
+	pt.SetTime(time.Unix(0, n*unit).UTC())
+	pt.timed = true
+
+	return pt, nil
+}
+
+func (pt *ParsedPoint) parseSeries() (err error) {
+	pt.Measurement, pt.Tags, err = SplitSeries(pt.series, pt.Tags)
+	return err
+}
+
+// SplitSeries returns the unescaped measurement of the escaped series key
+// of a point, and its unescaped tags appended to tags.
+func SplitSeries(series []byte, tags []Tag) (measurement []byte, _ []Tag, err error) {
+	i := scanTo(series, 0, ',', false)
+	measurement = Unescape(series[:i])
+	if len(measurement) == 0 {
+		return nil, tags, errors.New("missing measurement")
+	}
+
+	for i < len(series) {
+		start := i + 1
+		i = scanTo(series, start, ',', false)
+		part := series[start:i]
+
+		j := scanTo(part, 0, '=', false)
+		if j == 0 || j >= len(part)-1 {
+			return nil, tags, fmt.Errorf("invalid tag %s", part)
+		}
+
+		tags = append(tags, Tag{
+			Key:   Unescape(part[:j]),
+			Value: Unescape(part[j+1:]),
+		})
+	}
+
+	return measurement, tags, nil
+}
+
+func (pt *ParsedPoint) parseFields(b []byte) error {
+	if len(b) == 0 {
+		return errors.New("missing fields")
+	}
+
+	for _, part := range splitUnescaped(b, ',', true) {
+		i := scanTo(part, 0, '=', false)
+		if i == 0 || i >= len(part)-1 {
+			return fmt.Errorf("invalid field %s", part)
+		}
+
+		f, err := parseField(Unescape(part[:i]), part[i+1:])
+		if err != nil {
+			return err
+		}
+		pt.fields = append(pt.fields, f)
+	}
+
+	return nil
+}
+
+func parseField(key, v []byte) (Field, error) {
+	switch {
+	case v[0] == '"':
+		if len(v) < 2 || v[len(v)-1] != '"' || scanTo(v[1:len(v)-1], 0, '"', false) != len(v)-2 {
+			return nil, fmt.Errorf("invalid string %s", v)
+		}
+		return &String{Key: key, Value: unescapeString(v[1 : len(v)-1])}, nil
+	case v[len(v)-1] == 'i':
+		n, err := strconv.ParseInt(string(v[:len(v)-1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s", v)
+		}
+		return &Int{Key: key, Value: n}, nil
+	case v[len(v)-1] == 'u':
+		n, err := strconv.ParseUint(string(v[:len(v)-1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer %s", v)
+		}
+		return &Uint{Key: key, Value: n}, nil
+	}
+
+	switch string(v) {
+	case "t", "T", "true", "True", "TRUE":
+		return &Bool{Key: key, Value: true}, nil
+	case "f", "F", "false", "False", "FALSE":
+		return &Bool{Key: key, Value: false}, nil
+	}
+
+	n, err := strconv.ParseFloat(string(v), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, fmt.Errorf("invalid float %s", v)
+	}
+	return &Float{Key: key, Value: n}, nil
+}
+
+// unescapeString removes the escaping of double quotes and backslashes
+// from a string field value.
+func unescapeString(b []byte) string {
+	if bytes.IndexByte(b, '\\') == -1 {
+		return string(b)
+	}
+
+	u := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\\' && i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\') {
+			i++
+		}
+		u = append(u, b[i])
+	}
+
+	return string(u)
+}
+
+// scanTo returns the index of the first c in b at or after i that is not
+// escaped, and if quotes is true not inside a string, or len(b).
+func scanTo(b []byte, i int, c byte, quotes bool) int {
+	quoted := false
+	for ; i < len(b); i++ {
+		switch {
+		case b[i] == '\\':
+			i++
+		case quotes && b[i] == '"':
+			quoted = !quoted
+		case b[i] == c && !quoted:
+			return i
+		}
+	}
+
+	if i > len(b) {
+		return len(b)
+	}
+	return i
+}
+
+func skipSpaces(b []byte, i int) int {
+	for i < len(b) && b[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// splitUnescaped splits b on each sep that is not escaped, and if quotes
+// is true not inside a string.
+func splitUnescaped(b []byte, sep byte, quotes bool) [][]byte {
+	parts := [][]byte{}
+
+	for {
+		i := scanTo(b, 0, sep, quotes)
+		if i == len(b) {
+			return append(parts, b)
+		}
+		parts = append(parts, b[:i])
+		b = b[i+1:]
+	}
+}
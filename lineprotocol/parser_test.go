@@ -0,0 +1,209 @@
+package lineprotocol_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+)
+
+func TestParseLine(t *testing.T) {
+	line := `disk\ io,path=/var/lib\ docker,a\,b=c\=d used\ percent=1.5,n=-2i,u=3u,ok=t,msg="say \"hi\", ok" 1257894000`
+
+	pt, err := lineprotocol.ParseLine([]byte(line), lineprotocol.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := string(pt.Measurement), "disk io"; got != exp {
+		t.Errorf("Wrong measurement parsed. got %v, exp %v", got, exp)
+	}
+
+	expTags := []lineprotocol.Tag{
+		{Key: []byte("path"), Value: []byte("/var/lib docker")},
+		{Key: []byte("a,b"), Value: []byte("c=d")},
+	}
+	if got := pt.Tags; !reflect.DeepEqual(got, expTags) {
+		t.Errorf("Wrong tags parsed. got %q, exp %q", got, expTags)
+	}
+
+	expFields := []lineprotocol.Field{
+		&lineprotocol.Float{Key: []byte("used percent"), Value: 1.5},
+		&lineprotocol.Int{Key: []byte("n"), Value: -2},
+		&lineprotocol.Uint{Key: []byte("u"), Value: 3},
+		&lineprotocol.Bool{Key: []byte("ok"), Value: true},
+		&lineprotocol.String{Key: []byte("msg"), Value: `say "hi", ok`},
+	}
+	if got := pt.Fields(); !reflect.DeepEqual(got, expFields) {
+		t.Errorf("Wrong fields parsed. got %v, exp %v", got, expFields)
+	}
+
+	if got, exp := pt.Time().Time(), testTime; !got.Equal(exp) {
+		t.Errorf("Wrong time parsed. got %v, exp %v", got, exp)
+	}
+	if !pt.HasTime() {
+		t.Error("Point with a timestamp has no time")
+	}
+}
+
+func TestSplitSeries(t *testing.T) {
+	measurement, tags, err := lineprotocol.SplitSeries([]byte(`disk\ io,path=/var/lib\ docker,a\,b=c\=d`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := string(measurement), "disk io"; got != exp {
+		t.Errorf("Wrong measurement split. got %v, exp %v", got, exp)
+	}
+
+	expTags := []lineprotocol.Tag{
+		{Key: []byte("path"), Value: []byte("/var/lib docker")},
+		{Key: []byte("a,b"), Value: []byte("c=d")},
+	}
+	if !reflect.DeepEqual(tags, expTags) {
+		t.Errorf("Wrong tags split. got %q, exp %q", tags, expTags)
+	}
+
+	for _, series := range []string{"", ",host=a", "cpu,", "cpu,host", "cpu,=a", "cpu,host="} {
+		if _, _, err := lineprotocol.SplitSeries([]byte(series), nil); err == nil {
+			t.Errorf("Expected an error splitting %q", series)
+		}
+	}
+}
+
+func TestParseLine_NoTimestamp(t *testing.T) {
+	before := time.Now()
+	pt, err := lineprotocol.ParseLine([]byte("cpu a=1"), lineprotocol.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pt.Time().Time(); got.Before(before) {
+		t.Errorf("Point without timestamp was not given the parse time. got %v", got)
+	}
+	if pt.HasTime() {
+		t.Error("Point without timestamp has a time")
+	}
+}
+
+func TestParseLine_Invalid(t *testing.T) {
+	lines := []string{
+		"cpu",
+		"cpu ",
+		",host=a a=1",
+		"cpu,host a=1",
+		"cpu,host= a=1",
+		"cpu a",
+		"cpu a=",
+		"cpu =1",
+		"cpu a=1,",
+		`cpu a="abc`,
+		`cpu a="a"b"`,
+		"cpu a=1.5i",
+		"cpu a=-1u",
+		"cpu a=abc",
+		"cpu a=1 abc",
+		"cpu a=1 9223372036854775807",
+	}
+
+	for _, line := range lines {
+		_, err := lineprotocol.ParseLine([]byte(line), lineprotocol.Second)
+		if err == nil {
+			t.Errorf("Expected an error parsing %q", line)
+			continue
+		}
+
+		if _, ok := err.(*lineprotocol.ParseError); !ok {
+			t.Errorf("Expected a *ParseError parsing %q, got %T", line, err)
+		}
+	}
+}
+
+func TestParser_Next(t *testing.T) {
+	data := "# Batch 1:\ncpu a=1 1\n\ncpu a= 2\ncpu a=3 3\n"
+
+	p := lineprotocol.NewParser(strings.NewReader(data), lineprotocol.Nanosecond)
+
+	pt, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := pt.Time().Time().UnixNano(), int64(1); got != exp {
+		t.Errorf("Wrong point parsed. got time %v, exp %v", got, exp)
+	}
+
+	_, err = p.Next()
+	perr, ok := err.(*lineprotocol.ParseError)
+	if !ok {
+		t.Fatalf("Expected a *ParseError, got %v", err)
+	}
+	if got, exp := perr.Line, 4; got != exp {
+		t.Errorf("Wrong line number reported. got %v, exp %v", got, exp)
+	}
+
+	pt, err = p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := pt.Time().Time().UnixNano(), int64(3); got != exp {
+		t.Errorf("Wrong point parsed. got time %v, exp %v", got, exp)
+	}
+
+	if _, err := p.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+type fieldsPoint struct {
+	mockPoint
+	series []byte
+	fields []lineprotocol.Field
+}
+
+func (p *fieldsPoint) Series() []byte               { return p.series }
+func (p *fieldsPoint) Fields() []lineprotocol.Field { return p.fields }
+
+func TestParseLine_RoundTrip(t *testing.T) {
+	pts := []lineprotocol.Point{
+		NewMockPoint(),
+		&fieldsPoint{
+			series: []byte(`disk\ io,path=/var/lib\ docker`),
+			fields: []lineprotocol.Field{
+				&lineprotocol.Float{Key: []byte("used percent"), Value: -0.125},
+				&lineprotocol.Int{Key: []byte("a,b=c"), Value: -9223372036854775808},
+				&lineprotocol.Uint{Key: []byte("u"), Value: 18446744073709551615},
+				&lineprotocol.Bool{Key: []byte("ok"), Value: false},
+				&lineprotocol.String{Key: []byte("msg"), Value: `a "quoted", \escaped\ value`},
+			},
+		},
+	}
+
+	for _, p := range pts {
+		buf := bytes.NewBuffer(nil)
+		if err := lineprotocol.WritePoint(buf, p); err != nil {
+			t.Fatal(err)
+		}
+		exp := buf.String()
+
+		parsed, err := lineprotocol.ParsePoints(buf.Bytes(), lineprotocol.Nanosecond)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %v", exp, err)
+		}
+		if len(parsed) != 1 {
+			t.Fatalf("Wrong number of points parsed from %q: %v", exp, len(parsed))
+		}
+
+		buf.Reset()
+		if err := lineprotocol.WritePoint(buf, parsed[0]); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.String(); got != exp {
+			t.Errorf("Round trip changed the point. got %v, exp %v", got, exp)
+		}
+	}
+}
@@ -7,7 +7,8 @@ import (
 
 // Point defines values that will be written in line protocol.
 type Point interface {
-	// Byte slice representing the series key for a point.
+	// Byte slice representing the series key for a point. The
+	// measurement and tags must already be escaped.
 	Series() []byte
 
 	// Slice of Field (alais for io.WriterTo) interfaces.
@@ -20,9 +21,26 @@ type Point interface {
 	Update()
 }
 
+// SparsePoint is a Point that writes only some of its fields each time.
+type SparsePoint interface {
+	Point
+
+	// Present reports whether the field at index i of Fields is written.
+	Present(i int) bool
+}
+
 // WritePoint takes in an io.Writer and a Point and writes that point
-// to the writer.
+// to the writer. Only the present fields of a SparsePoint are written,
+// or its first field if none are present.
 func WritePoint(w io.Writer, p Point) (err error) {
+	fields := p.Fields()
+	sp, sparse := p.(SparsePoint)
+	if sparse && !anyPresent(sp, len(fields)) {
+		sparse = false
+		if len(fields) > 0 {
+			fields = fields[:1]
+		}
+	}
 
 	// Write the series key
 	_, err = w.Write(p.Series())
@@ -37,14 +55,21 @@ func WritePoint(w io.Writer, p Point) (err error) {
 	}
 
 	// Write each of the fields
-	for i, f := range p.Fields() {
-		if i != 0 {
+	n := 0
+	for i, f := range fields {
+		if sparse && !sp.Present(i) {
+			continue
+		}
+
+		if n != 0 {
 			// Add a comma for all but the first field
 			_, err = w.Write([]byte(","))
 			if err != nil {
 				return
 			}
 		}
+		n++
+
 		// Write the field to w
 		_, err = f.WriteTo(w)
 		if err != nil {
@@ -72,3 +97,34 @@ func WritePoint(w io.Writer, p Point) (err error) {
 
 	return
 }
+
+// WrittenFields appends the fields of p that WritePoint writes to fields.
+func WrittenFields(fields []Field, p Point) []Field {
+	all := p.Fields()
+	sp, sparse := p.(SparsePoint)
+	if !sparse || !anyPresent(sp, len(all)) {
+		if sparse && len(all) > 0 {
+			all = all[:1]
+		}
+		return append(fields, all...)
+	}
+
+	for i, f := range all {
+		if sp.Present(i) {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// anyPresent reports whether any of the first n fields of p is present.
+func anyPresent(p SparsePoint, n int) bool {
+	for i := 0; i < n; i++ {
+		if p.Present(i) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,6 +3,7 @@ package lineprotocol_test
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -63,3 +64,45 @@ func TestWritePoint_MockPoint(t *testing.T) {
 	}
 
 }
+
+type sparsePoint struct {
+	mockPoint
+	present []bool
+}
+
+func (p *sparsePoint) Present(i int) bool { return p.present[i] }
+
+func TestWritePoint_SparsePoint(t *testing.T) {
+	tests := []struct {
+		present []bool
+		fields  string
+	}{
+		{present: []bool{true, true}, fields: "a=100i,b=10"},
+		{present: []bool{false, true}, fields: "b=10"},
+		{present: []bool{true, false}, fields: "a=100i"},
+		// A line needs at least one field, so the first is written.
+		{present: []bool{false, false}, fields: "a=100i"},
+	}
+
+	for _, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		if err := lineprotocol.WritePoint(buf, &sparsePoint{present: test.present}); err != nil {
+			t.Fatal(err)
+		}
+
+		exp := fmt.Sprintf("cpu,host=server %s %v\n", test.fields, testTime.UnixNano())
+		if got := buf.String(); got != exp {
+			t.Errorf("Wrong data was written for %v. got %v, exp %v", test.present, got, exp)
+		}
+
+		var keys []string
+		for _, f := range lineprotocol.WrittenFields(nil, &sparsePoint{present: test.present}) {
+			b := bytes.NewBuffer(nil)
+			f.WriteTo(b)
+			keys = append(keys, b.String())
+		}
+		if got := strings.Join(keys, ","); got != test.fields {
+			t.Errorf("Wrong fields returned for %v. got %v, exp %v", test.present, got, test.fields)
+		}
+	}
+}
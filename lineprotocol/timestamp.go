@@ -1,6 +1,7 @@
 package lineprotocol
 
 import (
+	"fmt"
 	"io"
 	"strconv"
 	"sync/atomic"
@@ -12,12 +13,76 @@ import (
 type Precision int
 
 const (
-	Nanosecond = iota
+	Nanosecond Precision = iota
 	Second
+	Microsecond
+	Millisecond
+	Minute
+	Hour
 )
 
+// ParsePrecision returns the Precision for one of the precision strings
+// accepted by the InfluxDB write endpoint (n, u, ms, s, m or h).
+// The empty string and "ns" are treated as nanoseconds and "us" as microseconds,
+// and the names used by the v3 write API, such as "second", are accepted too.
+func ParsePrecision(s string) (Precision, error) {
+	switch s {
+	case "", "n", "ns", "nanosecond":
+		return Nanosecond, nil
+	case "u", "us", "microsecond":
+		return Microsecond, nil
+	case "ms", "millisecond":
+		return Millisecond, nil
+	case "s", "second":
+		return Second, nil
+	case "m":
+		return Minute, nil
+	case "h":
+		return Hour, nil
+	}
+
+	return Nanosecond, fmt.Errorf("unknown precision %q", s)
+}
+
+// Duration returns the length of one unit of the precision.
+func (p Precision) Duration() time.Duration {
+	switch p {
+	case Microsecond:
+		return time.Microsecond
+	case Millisecond:
+		return time.Millisecond
+	case Second:
+		return time.Second
+	case Minute:
+		return time.Minute
+	case Hour:
+		return time.Hour
+	}
+
+	return time.Nanosecond
+}
+
+// String returns the precision as it is written in the
+// precision query parameter of the InfluxDB write endpoint.
+func (p Precision) String() string {
+	switch p {
+	case Microsecond:
+		return "u"
+	case Millisecond:
+		return "ms"
+	case Second:
+		return "s"
+	case Minute:
+		return "m"
+	case Hour:
+		return "h"
+	}
+
+	return "n"
+}
+
 // Timestamp represents a timestamp in line protocol
-// in either second or nanosecond precision.
+// in any of the precisions supported by InfluxDB.
 type Timestamp struct {
 	precision Precision
 	ptr       unsafe.Pointer
@@ -30,6 +95,11 @@ func NewTimestamp(p Precision) *Timestamp {
 	}
 }
 
+// Precision returns the precision the timestamp is written in.
+func (t *Timestamp) Precision() Precision {
+	return t.precision
+}
+
 // TimePtr returns an unsafe.Pointer to an underlying
 // time.Time object.
 func (t *Timestamp) TimePtr() *unsafe.Pointer {
@@ -43,6 +113,11 @@ func (t *Timestamp) SetTime(ts *time.Time) {
 	atomic.StorePointer(&t.ptr, tsPtr)
 }
 
+// Time returns the time the timestamp was last set to.
+func (t *Timestamp) Time() time.Time {
+	return *(*time.Time)(atomic.LoadPointer(&t.ptr))
+}
+
 // WriteTo writes the timestamp to an io.Writer.
 func (t *Timestamp) WriteTo(w io.Writer) (int64, error) {
 	tsPtr := atomic.LoadPointer(&t.ptr)
@@ -50,8 +125,13 @@ func (t *Timestamp) WriteTo(w io.Writer) (int64, error) {
 	tsTime := *(*time.Time)(tsPtr)
 	ts := tsTime.UnixNano()
 
-	if t.precision == Second {
-		ts = tsTime.Unix()
+	if t.precision != Nanosecond {
+		unit := int64(t.precision.Duration())
+		// Round down, as time.Unix does, for times before the epoch.
+		if ts < 0 && ts%unit != 0 {
+			ts -= unit
+		}
+		ts /= unit
 	}
 
 	// Max int64 fits in 19 base-10 digits;
@@ -62,3 +62,67 @@ func TestTimestamp_WriteTo_Nanosecond(t *testing.T) {
 		return
 	}
 }
+
+func TestTimestamp_WriteTo_Precisions(t *testing.T) {
+	tests := []struct {
+		precision lineprotocol.Precision
+		exp       int64
+	}{
+		{lineprotocol.Microsecond, testTime.UnixNano() / int64(time.Microsecond)},
+		{lineprotocol.Millisecond, testTime.UnixNano() / int64(time.Millisecond)},
+		{lineprotocol.Minute, testTime.Unix() / 60},
+		{lineprotocol.Hour, testTime.Unix() / 3600},
+	}
+
+	for _, test := range tests {
+		ts := lineprotocol.NewTimestamp(test.precision)
+		ts.SetTime(&testTime)
+
+		buf := bytes.NewBuffer(nil)
+		if _, err := ts.WriteTo(buf); err != nil {
+			t.Error(err)
+			return
+		}
+
+		exp := fmt.Sprintf("%v", test.exp)
+		got := string(buf.Bytes())
+
+		if got != exp {
+			t.Errorf("Wrong timestamp written for precision %v. got %v, exp %v", test.precision, got, exp)
+		}
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	tests := map[string]lineprotocol.Precision{
+		"":   lineprotocol.Nanosecond,
+		"n":  lineprotocol.Nanosecond,
+		"ns": lineprotocol.Nanosecond,
+		"u":  lineprotocol.Microsecond,
+		"ms": lineprotocol.Millisecond,
+		"s":  lineprotocol.Second,
+		"m":  lineprotocol.Minute,
+		"h":  lineprotocol.Hour,
+
+		"nanosecond":  lineprotocol.Nanosecond,
+		"microsecond": lineprotocol.Microsecond,
+		"millisecond": lineprotocol.Millisecond,
+		"second":      lineprotocol.Second,
+	}
+
+	for s, exp := range tests {
+		got, err := lineprotocol.ParsePrecision(s)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", s, err)
+			continue
+		}
+
+		if got != exp {
+			t.Errorf("Wrong precision parsed from %q. got %v, exp %v", s, got, exp)
+		}
+	}
+
+	if _, err := lineprotocol.ParsePrecision("d"); err == nil {
+		t.Error("Expected an error parsing an unknown precision")
+	}
+}
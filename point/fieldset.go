@@ -1,21 +1,209 @@
 package point
 
-import "strings"
+import (
+	"fmt"
+	"strconv"
+	"strings"
 
-// TODO: add correct error handling/panic when appropriate
-func generateFieldSet(s string) ([]string, []string) {
-	ints := []string{}
-	floats := []string{}
+	"github.com/influxdata/influx-stress/lineprotocol"
+)
 
-	parts := strings.Split(s, ",")
+// FieldType is the line protocol type of a field.
+type FieldType int
+
+const (
+	Integer FieldType = iota
+	Float
+	String
+	Boolean
+	Unsigned
+)
+
+// FieldSpec describes a single field of a FIELDS template.
+type FieldSpec struct {
+	Key  string
+	Type FieldType
+
+	// Value is the value given in the template, without quotes or type
+	// suffix. Numeric fields ignore it and count up from 0 unless they have
+	// a generator. Boolean fields start from it and are toggled on every update.
+	Value string
+
+	// NewGenerator, if set, creates the Generator of each point's values
+	// for a numeric field.
+	NewGenerator func() Generator
+
+	// StringLength, if non-zero, makes a String field take random values
+	// of that length instead of Value.
+	StringLength int
+
+	// StringPool, if not empty, is the set of values a String field
+	// picks from on every update.
+	StringPool []string
+
+	// Presence, if non-zero, is the probability that the field is written
+	// on each update. Otherwise the field is always written.
+	Presence float64
+}
+
+// generateFieldSet parses a FIELDS template such as `a=0i,b=0,c=0u,ok=t,msg="abc"`.
+// Commas, equals signs and spaces in field keys may be escaped with a backslash.
+// A numeric value may be a generator followed by the type suffix, as in
+// `usage=randwalk(0,100,0.5),count=counter()i`, see ParseGenerator.
+// A value followed by `?p` is written with probability p, as in `err=0i?0.1`.
+func generateFieldSet(s string) ([]FieldSpec, error) {
+	fields := []FieldSpec{}
+
+	parts, err := splitFields(s)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, part := range parts {
-		if strings.HasSuffix(part, "i") {
-			ints = append(ints, strings.Split(part, "=")[0])
-			continue
+		i := indexUnescaped(part, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid field %q: expected key=value", part)
+		}
+
+		f := FieldSpec{Key: string(lineprotocol.Unescape([]byte(part[:i])))}
+		v, presence, err := splitPresence(part[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %v", part, err)
+		}
+		f.Presence = presence
+
+		switch {
+		case strings.HasPrefix(v, `"`):
+			f.Type = String
+			f.Value, err = unquote(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field %q: %v", part, err)
+			}
+		case isBool(v):
+			f.Type = Boolean
+			f.Value = strconv.FormatBool(v[0] == 't' || v[0] == 'T')
+		case strings.Contains(v, "("):
+			f.Type = Float
+			if strings.HasSuffix(v, "i") {
+				f.Type, v = Integer, strings.TrimSuffix(v, "i")
+			} else if strings.HasSuffix(v, "u") {
+				f.Type, v = Unsigned, strings.TrimSuffix(v, "u")
+			}
+			f.Value = v
+			f.NewGenerator, err = ParseGenerator(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field %q: %v", part, err)
+			}
+		case strings.HasSuffix(v, "i"):
+			f.Type = Integer
+			f.Value = strings.TrimSuffix(v, "i")
+			_, err = strconv.ParseInt(f.Value, 10, 64)
+		case strings.HasSuffix(v, "u"):
+			f.Type = Unsigned
+			f.Value = strings.TrimSuffix(v, "u")
+			_, err = strconv.ParseUint(f.Value, 10, 64)
+		default:
+			f.Type = Float
+			f.Value = v
+			_, err = strconv.ParseFloat(f.Value, 64)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: unrecognized value %s", part, v)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// splitPresence splits the `?p` presence probability off a field value.
+// It returns a presence of 0 if the value has none.
+func splitPresence(v string) (string, float64, error) {
+	i := strings.LastIndexByte(v, '?')
+	// A question mark inside a string value is part of the string.
+	if i < 0 || i < strings.LastIndexByte(v, '"') {
+		return v, 0, nil
+	}
+
+	p, err := strconv.ParseFloat(v[i+1:], 64)
+	if err != nil || !(p > 0 && p <= 1) {
+		return "", 0, fmt.Errorf("presence must be a probability in (0,1], got %q", v[i+1:])
+	}
+
+	return v[:i], p, nil
+}
+
+// isBool reports whether v is one of the boolean values line protocol accepts.
+func isBool(v string) bool {
+	switch v {
+	case "t", "T", "true", "True", "TRUE", "f", "F", "false", "False", "FALSE":
+		return true
+	}
+
+	return false
+}
+
+// splitFields splits a FIELDS template on the commas that are not
+// inside a quoted string value or the arguments of a generator.
+func splitFields(s string) ([]string, error) {
+	parts := []string{}
+
+	start := 0
+	quoted := false
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			// Skip the escaped character.
+			i++
+		case '"':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted {
+				depth--
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated string in fields %q", s)
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in fields %q", s)
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+// unquote removes the quotes around a string field value
+// and the escaping of any double quotes and backslashes in it.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+
+	s = s[1 : len(s)-1]
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+			i++
+		} else if s[i] == '"' {
+			return "", fmt.Errorf("unescaped quote in string %s", s)
 		}
-		floats = append(floats, strings.Split(part, "=")[0])
+		b = append(b, s[i])
 	}
 
-	return ints, floats
+	return string(b), nil
 }
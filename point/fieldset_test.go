@@ -5,38 +5,162 @@ import (
 	"testing"
 )
 
+func fieldKeys(fields []FieldSpec, typ FieldType) []string {
+	keys := []string{}
+	for _, f := range fields {
+		if f.Type == typ {
+			keys = append(keys, f.Key)
+		}
+	}
+
+	return keys
+}
+
 func TestGenerateFieldSet_onlyInts(t *testing.T) {
-	ints, floats := generateFieldSet("a=0i,fields=9i")
+	fields, err := generateFieldSet("a=0i,fields=9i")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if got, exp := len(floats), 0; exp != got {
+	if got, exp := len(fieldKeys(fields, Float)), 0; exp != got {
 		t.Errorf("Expected no floats. Got %v, Expected: %v\n", got, exp)
 	}
 
-	if got, exp := ints, []string{"a", "fields"}; !reflect.DeepEqual(got, exp) {
+	if got, exp := fieldKeys(fields, Integer), []string{"a", "fields"}; !reflect.DeepEqual(got, exp) {
 		t.Errorf("Wrong integer fields pulled. Got %v, Expected: %v\n", got, exp)
 	}
 }
 
 func TestGenerateFieldSet_onlyFloats(t *testing.T) {
-	ints, floats := generateFieldSet("b=0,things=9")
+	fields, err := generateFieldSet("b=0,things=9")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if got, exp := len(ints), 0; exp != got {
+	if got, exp := len(fieldKeys(fields, Integer)), 0; exp != got {
 		t.Errorf("Expected no ints. Got %v, Expected: %v\n", got, exp)
 	}
 
-	if got, exp := floats, []string{"b", "things"}; !reflect.DeepEqual(got, exp) {
+	if got, exp := fieldKeys(fields, Float), []string{"b", "things"}; !reflect.DeepEqual(got, exp) {
 		t.Errorf("Wrong float fields pulled. Got %v, Expected: %v\n", got, exp)
 	}
 }
 
 func TestGenerateFieldSet_mixed(t *testing.T) {
-	ints, floats := generateFieldSet("a=1i,b=0,fields=92i,things=9")
+	fields, err := generateFieldSet("a=1i,b=0,fields=92i,things=9")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if got, exp := ints, []string{"a", "fields"}; !reflect.DeepEqual(got, exp) {
+	if got, exp := fieldKeys(fields, Integer), []string{"a", "fields"}; !reflect.DeepEqual(got, exp) {
 		t.Errorf("Wrong integer fields pulled. Got %v, Expected: %v\n", got, exp)
 	}
 
-	if got, exp := floats, []string{"b", "things"}; !reflect.DeepEqual(got, exp) {
+	if got, exp := fieldKeys(fields, Float), []string{"b", "things"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong float fields pulled. Got %v, Expected: %v\n", got, exp)
+	}
+}
+
+func TestGenerateFieldSet_strings(t *testing.T) {
+	fields, err := generateFieldSet(`msg="a, \"quoted\" value",n=0i,status="ok"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []FieldSpec{
+		{Key: "msg", Type: String, Value: `a, "quoted" value`},
+		{Key: "n", Type: Integer, Value: "0"},
+		{Key: "status", Type: String, Value: "ok"},
+	}
+
+	if !reflect.DeepEqual(fields, exp) {
+		t.Errorf("Wrong fields pulled. Got %v, Expected: %v\n", fields, exp)
+	}
+}
+
+func TestGenerateFieldSet_boolsAndUints(t *testing.T) {
+	fields, err := generateFieldSet("up=t,down=FALSE,count=123u,n=1i")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []FieldSpec{
+		{Key: "up", Type: Boolean, Value: "true"},
+		{Key: "down", Type: Boolean, Value: "false"},
+		{Key: "count", Type: Unsigned, Value: "123"},
+		{Key: "n", Type: Integer, Value: "1"},
+	}
+
+	if !reflect.DeepEqual(fields, exp) {
+		t.Errorf("Wrong fields pulled. Got %v, Expected: %v\n", fields, exp)
+	}
+}
+
+func TestGenerateFieldSet_escapedKeys(t *testing.T) {
+	fields, err := generateFieldSet(`used\ percent=0,a\,b\=c=1i`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := fieldKeys(fields, Float), []string{"used percent"}; !reflect.DeepEqual(got, exp) {
 		t.Errorf("Wrong float fields pulled. Got %v, Expected: %v\n", got, exp)
 	}
+
+	if got, exp := fieldKeys(fields, Integer), []string{"a,b=c"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong integer fields pulled. Got %v, Expected: %v\n", got, exp)
+	}
+}
+
+func TestGenerateFieldSet_generators(t *testing.T) {
+	fields, err := generateFieldSet(`usage=randwalk(0,100,0.5),count=counter(5,2)i,total=counter()u,msg="f(a,b)"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := len(fields), 4; got != exp {
+		t.Fatalf("Wrong number of fields pulled. Got %v, Expected: %v\n", got, exp)
+	}
+
+	types := []FieldType{Float, Integer, Unsigned, String}
+	for i, f := range fields {
+		if f.Type != types[i] {
+			t.Errorf("Wrong type for field %v. Got %v, Expected: %v\n", f.Key, f.Type, types[i])
+		}
+	}
+
+	if fields[3].NewGenerator != nil {
+		t.Errorf("String field should not have a generator")
+	}
+
+	if got, exp := nextN(fields[1].NewGenerator(), 3), []float64{5, 7, 9}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong generator parsed. Got %v, Expected: %v\n", got, exp)
+	}
+}
+
+func TestGenerateFieldSet_presence(t *testing.T) {
+	fields, err := generateFieldSet(`a=0i?0.5,b=uniform(0,1)?0.25,msg="why?"?1,ok=t`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []float64{}
+	for _, f := range fields {
+		got = append(got, f.Presence)
+	}
+
+	if exp := []float64{0.5, 0.25, 1, 0}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong presence pulled. Got %v, Expected: %v\n", got, exp)
+	}
+
+	if got, exp := fields[2].Value, "why?"; got != exp {
+		t.Errorf("Wrong string value pulled. Got %v, Expected: %v\n", got, exp)
+	}
+}
+
+func TestGenerateFieldSet_invalid(t *testing.T) {
+	for _, s := range []string{`msg="abc`, "n", "=1", `msg="a"b"`, "a=xyz", "a=-1u", "a=1.5i", "a=counter(", "a=nope()i", "a=0?0", "a=0?1.5", "a=0?x"} {
+		if _, err := generateFieldSet(s); err == nil {
+			t.Errorf("Expected an error parsing %q", s)
+		}
+	}
 }
@@ -0,0 +1,229 @@
+package point
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// A Generator produces the successive values of a numeric field.
+type Generator interface {
+	// Next returns the next value, taking any randomness it needs from r.
+	Next(r *rand.Rand) float64
+}
+
+// generatorKinds maps the name of each kind of Generator to the number of
+// arguments it takes, and a constructor taking those arguments.
+var generatorKinds = map[string]struct {
+	minArgs, maxArgs int
+	new              func(args []float64) (Generator, error)
+}{
+	"constant":  {1, 1, newConstant},
+	"counter":   {0, 2, newCounter},
+	"uniform":   {2, 2, newUniform},
+	"normal":    {2, 2, newNormal},
+	"randwalk":  {3, 3, newRandomWalk},
+	"sine":      {3, 3, newSine},
+	"monotonic": {2, 2, newMonotonic},
+}
+
+// ParseGenerator parses a generator such as `randwalk(0,100,0.5)` and returns
+// a function that creates a new, independent Generator each time it is called.
+//
+// The kinds of generator are
+//
+//	constant(v)                 always v
+//	counter([start[,step]])     start, start+step, ... (default 0 and 1)
+//	uniform(min,max)            uniformly distributed in [min,max)
+//	normal(mean,stddev)         normally distributed
+//	randwalk(min,max,step)      random walk in [min,max], moving at most step each time
+//	sine(min,max,period)        sine wave between min and max, repeating every period values
+//	monotonic(step,reset)       increases by up to step, and falls back to 0 with probability reset
+func ParseGenerator(s string) (func() Generator, error) {
+	open := strings.IndexByte(s, '(')
+	if open == -1 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("invalid generator %q: expected kind(args...)", s)
+	}
+
+	name := s[:open]
+	kind, ok := generatorKinds[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q", name)
+	}
+
+	args := []float64{}
+	if argStr := strings.TrimSpace(s[open+1 : len(s)-1]); argStr != "" {
+		for _, a := range strings.Split(argStr, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid argument %q to generator %s", a, name)
+			}
+			args = append(args, v)
+		}
+	}
+
+	if len(args) < kind.minArgs || len(args) > kind.maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments to generator %s: got %d", name, len(args))
+	}
+
+	// Check the arguments once up front, so creating a Generator per point can't fail.
+	if _, err := kind.new(args); err != nil {
+		return nil, fmt.Errorf("invalid generator %q: %v", s, err)
+	}
+
+	return func() Generator {
+		g, _ := kind.new(args)
+		return g
+	}, nil
+}
+
+// defaultGenerator is used for numeric fields without a generator.
+// It keeps the values going up by one on every update.
+func defaultGenerator() Generator {
+	return &counter{step: 1}
+}
+
+type constant float64
+
+func newConstant(args []float64) (Generator, error) {
+	return constant(args[0]), nil
+}
+
+func (c constant) Next(*rand.Rand) float64 {
+	return float64(c)
+}
+
+type counter struct {
+	value, step float64
+}
+
+func newCounter(args []float64) (Generator, error) {
+	c := &counter{step: 1}
+	if len(args) > 0 {
+		c.value = args[0]
+	}
+	if len(args) > 1 {
+		c.step = args[1]
+	}
+
+	return c, nil
+}
+
+func (c *counter) Next(*rand.Rand) float64 {
+	v := c.value
+	c.value += c.step
+	return v
+}
+
+type uniform struct {
+	min, max float64
+}
+
+func newUniform(args []float64) (Generator, error) {
+	if args[0] > args[1] {
+		return nil, fmt.Errorf("min %v is greater than max %v", args[0], args[1])
+	}
+
+	return &uniform{min: args[0], max: args[1]}, nil
+}
+
+func (u *uniform) Next(r *rand.Rand) float64 {
+	return u.min + r.Float64()*(u.max-u.min)
+}
+
+type normal struct {
+	mean, stddev float64
+}
+
+func newNormal(args []float64) (Generator, error) {
+	if args[1] < 0 {
+		return nil, fmt.Errorf("negative standard deviation %v", args[1])
+	}
+
+	return &normal{mean: args[0], stddev: args[1]}, nil
+}
+
+func (n *normal) Next(r *rand.Rand) float64 {
+	return n.mean + r.NormFloat64()*n.stddev
+}
+
+type randomWalk struct {
+	min, max, step float64
+	value          float64
+	started        bool
+}
+
+func newRandomWalk(args []float64) (Generator, error) {
+	if args[0] > args[1] {
+		return nil, fmt.Errorf("min %v is greater than max %v", args[0], args[1])
+	}
+
+	return &randomWalk{min: args[0], max: args[1], step: args[2]}, nil
+}
+
+func (w *randomWalk) Next(r *rand.Rand) float64 {
+	if !w.started {
+		// Start each walk somewhere different, so the series don't all look the same.
+		w.value = w.min + r.Float64()*(w.max-w.min)
+		w.started = true
+	}
+
+	v := w.value
+	w.value = math.Max(w.min, math.Min(w.max, w.value+(2*r.Float64()-1)*w.step))
+	return v
+}
+
+type sine struct {
+	mid, amplitude float64
+	period, n      float64
+	started        bool
+}
+
+func newSine(args []float64) (Generator, error) {
+	if args[2] <= 0 {
+		return nil, fmt.Errorf("period %v is not positive", args[2])
+	}
+
+	return &sine{
+		mid:       (args[0] + args[1]) / 2,
+		amplitude: (args[1] - args[0]) / 2,
+		period:    args[2],
+	}, nil
+}
+
+func (s *sine) Next(r *rand.Rand) float64 {
+	if !s.started {
+		// Start each wave at a different phase, so the series don't all look the same.
+		s.n = math.Floor(r.Float64() * s.period)
+		s.started = true
+	}
+
+	v := s.mid + s.amplitude*math.Sin(2*math.Pi*s.n/s.period)
+	s.n++
+	return v
+}
+
+type monotonic struct {
+	step, reset float64
+	value       float64
+}
+
+func newMonotonic(args []float64) (Generator, error) {
+	if args[1] < 0 || args[1] > 1 {
+		return nil, fmt.Errorf("reset probability %v is not between 0 and 1", args[1])
+	}
+
+	return &monotonic{step: args[0], reset: args[1]}, nil
+}
+
+func (m *monotonic) Next(r *rand.Rand) float64 {
+	v := m.value
+	if r.Float64() < m.reset {
+		m.value = 0
+	} else {
+		m.value += r.Float64() * m.step
+	}
+	return v
+}
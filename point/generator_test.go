@@ -0,0 +1,137 @@
+package point
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func nextN(g Generator, n int) []float64 {
+	r := rand.New(rand.NewSource(1))
+	vs := []float64{}
+	for i := 0; i < n; i++ {
+		vs = append(vs, g.Next(r))
+	}
+
+	return vs
+}
+
+func TestParseGenerator_Sequences(t *testing.T) {
+	tests := map[string][]float64{
+		"constant(2.5)":   {2.5, 2.5, 2.5},
+		"counter()":       {0, 1, 2},
+		"counter(10)":     {10, 11, 12},
+		"counter(10, -2)": {10, 8, 6},
+	}
+
+	for s, exp := range tests {
+		newGen, err := ParseGenerator(s)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %v: %v", s, err)
+			continue
+		}
+
+		if got := nextN(newGen(), len(exp)); !reflect.DeepEqual(got, exp) {
+			t.Errorf("Wrong values generated by %v. Got %v Expected %v", s, got, exp)
+		}
+	}
+}
+
+func TestParseGenerator_Ranges(t *testing.T) {
+	tests := []struct {
+		spec     string
+		min, max float64
+	}{
+		{"uniform(-5,5)", -5, 5},
+		{"randwalk(0,100,0.5)", 0, 100},
+		{"sine(10,20,60)", 10, 20},
+	}
+
+	for _, test := range tests {
+		newGen, err := ParseGenerator(test.spec)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %v: %v", test.spec, err)
+			continue
+		}
+
+		for _, v := range nextN(newGen(), 1000) {
+			if v < test.min || v > test.max {
+				t.Errorf("Value generated by %v out of range: %v", test.spec, v)
+				break
+			}
+		}
+	}
+}
+
+func TestParseGenerator_RandomWalkSteps(t *testing.T) {
+	newGen, err := ParseGenerator("randwalk(0,100,0.5)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs := nextN(newGen(), 1000)
+	for i := 1; i < len(vs); i++ {
+		if d := vs[i] - vs[i-1]; d > 0.5 || d < -0.5 {
+			t.Fatalf("Random walk moved more than the step: %v to %v", vs[i-1], vs[i])
+		}
+	}
+}
+
+func TestParseGenerator_Monotonic(t *testing.T) {
+	newGen, err := ParseGenerator("monotonic(10,0.01)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resets := 0
+	vs := nextN(newGen(), 10000)
+	for i := 1; i < len(vs); i++ {
+		if vs[i] < vs[i-1] {
+			if vs[i] != 0 {
+				t.Fatalf("Monotonic value went down without resetting: %v to %v", vs[i-1], vs[i])
+			}
+			resets++
+		}
+	}
+
+	if resets == 0 {
+		t.Errorf("Monotonic value never reset")
+	}
+}
+
+func TestParseGenerator_Normal(t *testing.T) {
+	newGen, err := ParseGenerator("normal(50,1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := 0.0
+	for _, v := range nextN(newGen(), 10000) {
+		sum += v
+	}
+
+	if mean := sum / 10000; mean < 49.9 || mean > 50.1 {
+		t.Errorf("Mean of normal values is off. Got %v Expected %v", mean, 50)
+	}
+}
+
+func TestParseGenerator_Invalid(t *testing.T) {
+	specs := []string{
+		"counter",
+		"counter(",
+		"unknown(1)",
+		"constant()",
+		"uniform(1)",
+		"uniform(5,1)",
+		"uniform(a,b)",
+		"normal(0,-1)",
+		"sine(0,1,0)",
+		"monotonic(1,2)",
+	}
+
+	for _, s := range specs {
+		if _, err := ParseGenerator(s); err == nil {
+			t.Errorf("Expected an error parsing %v", s)
+		}
+	}
+}
@@ -1,49 +1,134 @@
 package point
 
 import (
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influx-stress/lineprotocol"
 )
 
+// Config holds the options NewPoints uses to generate points.
+type Config struct {
+	Precision lineprotocol.Precision
+
+	// StringLength, if non-zero, replaces the template value of String
+	// fields with random values of the given length.
+	StringLength int
+
+	// StringPool, if non-zero, limits each String field to that many
+	// distinct values. Otherwise a String field with StringLength set
+	// takes a new value on every update.
+	StringPool int
+
+	// Rand, if not nil, is the source of randomness of the string pools
+	// and the points, until they are given another one with SetRand.
+	// It must not be shared between goroutines.
+	Rand *rand.Rand
+}
+
 // The point struct implements the lineprotocol.Point interface.
 type point struct {
 	seriesKey []byte
 
-	// Note here that Ints and Floats are exported so they can be modified outside
-	// of the point struct
-	Ints   []*lineprotocol.Int
-	Floats []*lineprotocol.Float
+	// baseKey is the series key the point was created with, and generation
+	// the number of times Churn has replaced it.
+	baseKey    []byte
+	generation int
 
-	// The fields slice should contain exactly Ints and Floats. Having this
-	// slice allows us to avoid iterating through Ints and Floats in the Fields
-	// function.
+	// Note here that the typed field slices are exported so they can be
+	// modified outside of the point struct
+	Ints    []*lineprotocol.Int
+	Uints   []*lineprotocol.Uint
+	Floats  []*lineprotocol.Float
+	Strings []*lineprotocol.String
+	Bools   []*lineprotocol.Bool
+
+	// The generators of the Int, Uint and Float fields, in the same order
+	// as Ints, Uints and Floats.
+	intGens   []Generator
+	uintGens  []Generator
+	floatGens []Generator
+
+	// stringSpecs holds the specs that the String fields are generated from,
+	// in the same order as Strings.
+	stringSpecs []FieldSpec
+
+	// The fields slice should contain exactly the fields of the typed slices.
+	// Having this slice allows us to avoid iterating through each of them
+	// in the Fields function.
 	fields []lineprotocol.Field
 
+	// presence holds the Presence of each field in fields, and present
+	// whether the field is written until the next update.
+	presence []float64
+	present  []bool
+
+	// rand is the source of randomness of the point's values.
+	rand *rand.Rand
+
 	time *lineprotocol.Timestamp
 }
 
-// New returns a new point without setting the time field.
-func New(sk []byte, ints, floats []string, p lineprotocol.Precision) *point {
+// New returns a new point without setting the time field. The point takes
+// its randomness from a source shared by all goroutines until SetRand is called.
+func New(sk []byte, specs []FieldSpec, p lineprotocol.Precision) *point {
+	return newPoint(sk, specs, p, globalRand)
+}
+
+// newPoint returns a new point taking its randomness from r.
+func newPoint(sk []byte, specs []FieldSpec, p lineprotocol.Precision, r *rand.Rand) *point {
 	fields := []lineprotocol.Field{}
 	e := &point{
 		seriesKey: sk,
+		baseKey:   sk,
 		time:      lineprotocol.NewTimestamp(p),
 		fields:    fields,
+		rand:      r,
 	}
 
-	for _, i := range ints {
-		n := &lineprotocol.Int{Key: []byte(i)}
-		e.Ints = append(e.Ints, n)
-		e.fields = append(e.fields, n)
-	}
+	for _, spec := range specs {
+		newGen := spec.NewGenerator
+		if newGen == nil {
+			newGen = defaultGenerator
+		}
 
-	for _, f := range floats {
-		n := &lineprotocol.Float{Key: []byte(f)}
-		e.Floats = append(e.Floats, n)
-		e.fields = append(e.fields, n)
+		switch spec.Type {
+		case Integer:
+			g := newGen()
+			n := &lineprotocol.Int{Key: []byte(spec.Key), Value: toInt(g.Next(r))}
+			e.Ints = append(e.Ints, n)
+			e.intGens = append(e.intGens, g)
+			e.fields = append(e.fields, n)
+		case Unsigned:
+			g := newGen()
+			n := &lineprotocol.Uint{Key: []byte(spec.Key), Value: toUint(g.Next(r))}
+			e.Uints = append(e.Uints, n)
+			e.uintGens = append(e.uintGens, g)
+			e.fields = append(e.fields, n)
+		case Float:
+			g := newGen()
+			n := &lineprotocol.Float{Key: []byte(spec.Key), Value: g.Next(r)}
+			e.Floats = append(e.Floats, n)
+			e.floatGens = append(e.floatGens, g)
+			e.fields = append(e.fields, n)
+		case String:
+			n := &lineprotocol.String{Key: []byte(spec.Key), Value: nextString(spec, r)}
+			e.Strings = append(e.Strings, n)
+			e.stringSpecs = append(e.stringSpecs, spec)
+			e.fields = append(e.fields, n)
+		case Boolean:
+			n := &lineprotocol.Bool{Key: []byte(spec.Key), Value: spec.Value == "true"}
+			e.Bools = append(e.Bools, n)
+			e.fields = append(e.fields, n)
+		}
+		e.presence = append(e.presence, spec.Presence)
 	}
+	e.present = make([]bool, len(e.presence))
+	e.updatePresence()
 
 	return e
 }
@@ -58,6 +143,17 @@ func (p *point) Fields() []lineprotocol.Field {
 	return p.fields
 }
 
+// SetRand makes the point take its randomness from r, which must not be
+// shared with points updated by other goroutines.
+func (p *point) SetRand(r *rand.Rand) {
+	p.rand = r
+}
+
+// Present reports whether the field at index i of Fields is written.
+func (p *point) Present(i int) bool {
+	return p.present[i]
+}
+
 // Time returns the timestamps for a point.
 func (p *point) Time() *lineprotocol.Timestamp {
 	return p.time
@@ -68,29 +164,163 @@ func (p *point) SetTime(t time.Time) {
 	p.time.SetTime(&t)
 }
 
-// Update increments the value of all of the Int and Float
-// fields by 1.
+// Churn replaces the series of the point with one that has not been written
+// before, by appending "-g<generation>" to the last tag value of the series
+// key it was created with. A key without tags is given a gen tag instead, so
+// that the measurement keeps its name.
+func (p *point) Churn() {
+	p.generation++
+	sk := make([]byte, 0, len(p.baseKey)+8)
+	sk = append(sk, p.baseKey...)
+	if indexUnescaped(string(p.baseKey), ',') < 0 {
+		sk = append(sk, ",gen="...)
+	} else {
+		sk = append(sk, "-g"...)
+	}
+	p.seriesKey = strconv.AppendInt(sk, int64(p.generation), 10)
+}
+
+// Update sets the Int, Uint and Float fields to the next value of their
+// generators, which by default increment them by 1. It toggles the Bool
+// fields, picks new values for generated String fields and decides which
+// fields with a Presence are written.
 func (p *point) Update() {
-	for _, i := range p.Ints {
-		atomic.AddInt64(&i.Value, int64(1))
+	p.updatePresence()
+
+	for i, n := range p.Ints {
+		atomic.StoreInt64(&n.Value, toInt(p.intGens[i].Next(p.rand)))
 	}
 
-	for _, f := range p.Floats {
+	for i, u := range p.Uints {
+		atomic.StoreUint64(&u.Value, toUint(p.uintGens[i].Next(p.rand)))
+	}
+
+	for i, f := range p.Floats {
 		// Need to do something else here
 		// There will be a race here
-		f.Value += 1.0
+		f.Value = p.floatGens[i].Next(p.rand)
+	}
+
+	for i, s := range p.Strings {
+		s.Value = nextString(p.stringSpecs[i], p.rand)
 	}
+
+	for _, b := range p.Bools {
+		b.Value = !b.Value
+	}
+}
+
+// updatePresence decides which fields are written until the next update.
+func (p *point) updatePresence() {
+	for i, pr := range p.presence {
+		p.present[i] = pr == 0 || p.rand.Float64() < pr
+	}
+}
+
+// toInt rounds a generated value to an int64.
+func toInt(v float64) int64 {
+	return int64(math.Round(v))
+}
+
+// toUint rounds a generated value to a uint64, with negative values becoming 0.
+func toUint(v float64) uint64 {
+	if v < 0 {
+		return 0
+	}
+	return uint64(math.Round(v))
 }
 
 // NewPoints returns a slice of Points of length seriesN shaped like the given seriesKey.
-func NewPoints(seriesKey, fields string, seriesN int, pc lineprotocol.Precision) []lineprotocol.Point {
+func NewPoints(seriesKey, fields string, seriesN int, cfg Config) ([]lineprotocol.Point, error) {
 	pts := []lineprotocol.Point{}
-	series := generateSeriesKeys(seriesKey, seriesN)
-	ints, floats := generateFieldSet(fields)
+	series, err := generateSeriesKeys(seriesKey, seriesN)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := generateFieldSet(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	r := cfg.Rand
+	if r == nil {
+		r = globalRand
+	}
+
+	for i := range specs {
+		if specs[i].Type == String {
+			specs[i].StringLength = cfg.StringLength
+			specs[i].StringPool = stringPool(specs[i].Value, cfg.StringLength, cfg.StringPool, r)
+		}
+	}
+
 	for _, sk := range series {
-		p := New(sk, ints, floats, pc)
+		p := newPoint(sk, specs, cfg.Precision, r)
 		pts = append(pts, p)
 	}
 
-	return pts
+	return pts, nil
+}
+
+// stringPool returns n values to use for a String field. The values are
+// random strings of the given length, or if length is 0 the template
+// value with a numeric suffix.
+func stringPool(value string, length, n int, r *rand.Rand) []string {
+	pool := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if length > 0 {
+			pool = append(pool, randomString(length, r))
+		} else {
+			pool = append(pool, value+"-"+strconv.Itoa(i))
+		}
+	}
+
+	return pool
+}
+
+// nextString returns a value for a String field described by spec.
+func nextString(spec FieldSpec, r *rand.Rand) string {
+	if len(spec.StringPool) > 0 {
+		return spec.StringPool[r.Intn(len(spec.StringPool))]
+	}
+
+	if spec.StringLength > 0 {
+		return randomString(spec.StringLength, r)
+	}
+
+	return spec.Value
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randomString(n int, r *rand.Rand) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+
+	return string(b)
+}
+
+// globalRand is the source of randomness of points that have not been given
+// one. Like the top-level functions of math/rand, it is safe for concurrent use.
+var globalRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
 }
@@ -3,6 +3,7 @@ package point_test
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,10 +17,17 @@ var (
 
 func TestPoint(t *testing.T) {
 	sk := []byte("cpu,host=server")
-	ints := []string{"user", "system"}
-	floats := []string{"busy", "wait"}
+	fields := []point.FieldSpec{
+		{Key: "user", Type: point.Integer},
+		{Key: "system", Type: point.Integer},
+		{Key: "busy", Type: point.Float},
+		{Key: "wait", Type: point.Float},
+		{Key: "status", Type: point.String, Value: "ok"},
+		{Key: "count", Type: point.Unsigned},
+		{Key: "up", Type: point.Boolean, Value: "true"},
+	}
 
-	p := point.New(sk, ints, floats, lineprotocol.Nanosecond)
+	p := point.New(sk, fields, lineprotocol.Nanosecond)
 	p.SetTime(testTime)
 
 	buf := bytes.NewBuffer(nil)
@@ -30,7 +38,7 @@ func TestPoint(t *testing.T) {
 			return
 		}
 
-		exp := fmt.Sprintf("cpu,host=server user=%vi,system=%vi,busy=%v,wait=%v %v\n", i, i, i, i, testTime.UnixNano())
+		exp := fmt.Sprintf("cpu,host=server user=%vi,system=%vi,busy=%v,wait=%v,status=\"ok\",count=%vu,up=%v %v\n", i, i, i, i, i, i%2 == 0, testTime.UnixNano())
 		got := string(buf.Bytes())
 
 		if got != exp {
@@ -42,3 +50,127 @@ func TestPoint(t *testing.T) {
 		buf.Reset()
 	}
 }
+
+func TestNewPoints_StringPool(t *testing.T) {
+	cfg := point.Config{
+		Precision:    lineprotocol.Nanosecond,
+		StringLength: 8,
+		StringPool:   3,
+	}
+
+	pts, err := point.NewPoints("cpu,host=server", `msg="abc"`, 10, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]bool{}
+	buf := bytes.NewBuffer(nil)
+	for _, p := range pts {
+		for i := 0; i < 10; i++ {
+			buf.Reset()
+			p.Fields()[0].WriteTo(buf)
+			if got, exp := buf.Len(), len(`msg=""`)+8; got != exp {
+				t.Fatalf("Wrong length of string field written. got %v, exp %v", got, exp)
+			}
+			values[buf.String()] = true
+			p.Update()
+		}
+	}
+
+	if got := len(values); got > 3 {
+		t.Errorf("Too many distinct string values written. got %v, exp at most %v", got, 3)
+	}
+}
+
+func TestNewPoints_RoundTrip(t *testing.T) {
+	pts, err := point.NewPoints(`disk\ io,path=/var/lib\ docker`, `used\ percent=0,n=0i,u=0u,ok=t,msg="a \"quoted\", value"`, 4, point.Config{Precision: lineprotocol.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, p := range pts {
+		p.SetTime(testTime)
+		p.Update()
+		if err := lineprotocol.WritePoint(buf, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := buf.String()
+
+	parsed, err := lineprotocol.ParsePoints(buf.Bytes(), lineprotocol.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	for _, p := range parsed {
+		if err := lineprotocol.WritePoint(buf, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := buf.String(); got != exp {
+		t.Errorf("Round trip changed the points. got %v, exp %v", got, exp)
+	}
+}
+
+func TestPoint_Churn(t *testing.T) {
+	p := point.New([]byte("cpu,host=server-1"), nil, lineprotocol.Nanosecond)
+
+	for _, exp := range []string{"cpu,host=server-1-g1", "cpu,host=server-1-g2"} {
+		p.Churn()
+		if got := string(p.Series()); got != exp {
+			t.Errorf("Wrong series after churn. got %v, exp %v", got, exp)
+		}
+	}
+}
+
+func TestPoint_ChurnTagless(t *testing.T) {
+	p := point.New([]byte(`disk\,io`), nil, lineprotocol.Nanosecond)
+
+	for _, exp := range []string{`disk\,io,gen=1`, `disk\,io,gen=2`} {
+		p.Churn()
+		if got := string(p.Series()); got != exp {
+			t.Errorf("Wrong series after churn. got %v, exp %v", got, exp)
+		}
+	}
+}
+
+func TestPoint_Presence(t *testing.T) {
+	fields := []point.FieldSpec{
+		{Key: "a", Type: point.Integer},
+		{Key: "b", Type: point.Integer, Presence: 0.5},
+		{Key: "c", Type: point.Integer, Presence: 0.01},
+	}
+
+	p := point.New([]byte("cpu"), fields, lineprotocol.Nanosecond)
+	p.SetTime(testTime)
+
+	const n = 10000
+	seen := map[string]int{}
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < n; i++ {
+		buf.Reset()
+		if err := lineprotocol.WritePoint(buf, p); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, f := range strings.Split(strings.Fields(buf.String())[1], ",") {
+			seen[strings.SplitN(f, "=", 2)[0]]++
+		}
+		p.Update()
+	}
+
+	if got := seen["a"]; got != n {
+		t.Errorf("Field a written %v times, exp %v", got, n)
+	}
+
+	if got := seen["b"]; got < n*45/100 || got > n*55/100 {
+		t.Errorf("Field b written %v times, exp about %v", got, n/2)
+	}
+
+	if got := seen["c"]; got < n/200 || got > n*2/100 {
+		t.Errorf("Field c written %v times, exp about %v", got, n/100)
+	}
+}
@@ -0,0 +1,248 @@
+package point
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+)
+
+// maxSampleSeries is the largest number of series that InferTemplates lists
+// in a template. Measurements with more series are written with the tags
+// taking the values seen independently of each other.
+const maxSampleSeries = 1000
+
+// maxSampleValues is the largest number of distinct values of a tag that
+// InferTemplates lists in a template. Tags with more values are written
+// with their cardinality only.
+const maxSampleValues = 100
+
+// sampleMeasurement accumulates what a sample shows about a measurement.
+type sampleMeasurement struct {
+	name   string
+	points int
+
+	// series are the escaped series keys seen, up to maxSampleSeries of them,
+	// and seen is the set of all of them.
+	series []string
+	seen   map[string]bool
+
+	// tags and fields are in the order they were first seen.
+	tags   []*sampleTag
+	fields []*sampleField
+}
+
+// sampleTag holds the distinct values of a tag, the first maxSampleValues+1
+// of them in values and all of them in seen.
+type sampleTag struct {
+	key    string
+	values []string
+	seen   map[string]bool
+}
+
+type sampleField struct {
+	key      string
+	typ      FieldType
+	count    int
+	min, max float64
+	example  string
+}
+
+// InferTemplates reads line protocol from r and returns a Template for
+// each measurement in it, in the order they first appear, shaped like the
+// points of the measurement:
+//
+//   - the series are the ones seen if there are at most maxSampleSeries of
+//     them, and otherwise each tag has the cardinality it has in the sample,
+//     listing the values seen if there are at most maxSampleValues of them;
+//   - numeric fields are uniform between the smallest and largest values seen,
+//     and keep their type;
+//   - string fields always have the first value seen, and booleans toggle;
+//   - fields missing from some points are written with the same probability;
+//   - the weight of each template is its number of points in the sample.
+//
+// The SeriesN of each template is the number of series of the measurement in
+// the sample.
+func InferTemplates(r io.Reader) ([]Template, error) {
+	measurements := []*sampleMeasurement{}
+	byName := map[string]*sampleMeasurement{}
+
+	parser := lineprotocol.NewParser(r, lineprotocol.Nanosecond)
+	for {
+		pt, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byName[string(pt.Measurement)]
+		if !ok {
+			m = &sampleMeasurement{name: string(pt.Measurement), seen: map[string]bool{}}
+			byName[m.name] = m
+			measurements = append(measurements, m)
+		}
+		m.add(pt)
+	}
+
+	templates := make([]Template, 0, len(measurements))
+	for _, m := range measurements {
+		templates = append(templates, m.template())
+	}
+
+	return templates, nil
+}
+
+// add records the tags and fields of pt.
+func (m *sampleMeasurement) add(pt *lineprotocol.ParsedPoint) {
+	m.points++
+
+	key := lineprotocol.EscapeMeasurement([]byte(m.name))
+	for _, t := range pt.Tags {
+		tag := m.tag(string(t.Key))
+		if v := string(t.Value); !tag.seen[v] {
+			tag.seen[v] = true
+			if len(tag.values) <= maxSampleValues {
+				tag.values = append(tag.values, v)
+			}
+		}
+
+		key = append(key, ',')
+		key = append(key, lineprotocol.EscapeTag(t.Key)...)
+		key = append(key, '=')
+		key = append(key, lineprotocol.EscapeTag(t.Value)...)
+	}
+	if !m.seen[string(key)] {
+		m.seen[string(key)] = true
+		if len(m.series) < maxSampleSeries {
+			m.series = append(m.series, string(key))
+		}
+	}
+
+	for _, f := range pt.Fields() {
+		switch f := f.(type) {
+		case *lineprotocol.Int:
+			m.field(string(f.Key), Integer).addValue(float64(f.Value))
+		case *lineprotocol.Uint:
+			m.field(string(f.Key), Unsigned).addValue(float64(f.Value))
+		case *lineprotocol.Float:
+			m.field(string(f.Key), Float).addValue(f.Value)
+		case *lineprotocol.Bool:
+			m.field(string(f.Key), Boolean).count++
+		case *lineprotocol.String:
+			field := m.field(string(f.Key), String)
+			if field.count == 0 {
+				field.example = f.Value
+			}
+			field.count++
+		}
+	}
+}
+
+// tag returns the tag with the given key, adding it if it is new.
+func (m *sampleMeasurement) tag(key string) *sampleTag {
+	for _, t := range m.tags {
+		if t.key == key {
+			return t
+		}
+	}
+
+	t := &sampleTag{key: key, seen: map[string]bool{}}
+	m.tags = append(m.tags, t)
+	return t
+}
+
+// field returns the field with the given key, adding it with the type typ if
+// it is new. A field keeps the type it was first seen with.
+func (m *sampleMeasurement) field(key string, typ FieldType) *sampleField {
+	for _, f := range m.fields {
+		if f.key == key {
+			return f
+		}
+	}
+
+	f := &sampleField{key: key, typ: typ, min: math.Inf(1), max: math.Inf(-1)}
+	m.fields = append(m.fields, f)
+	return f
+}
+
+func (f *sampleField) addValue(v float64) {
+	f.count++
+	f.min = math.Min(f.min, v)
+	f.max = math.Max(f.max, v)
+}
+
+// template returns the Template of the measurement.
+func (m *sampleMeasurement) template() Template {
+	var series string
+	if len(m.seen) <= maxSampleSeries {
+		keys := make([]string, 0, len(m.series))
+		for _, key := range m.series {
+			keys = append(keys, strings.NewReplacer("{", `\{`, "}", `\}`).Replace(key))
+		}
+		series = "{" + strings.Join(keys, " ") + "}"
+	} else {
+		series = string(lineprotocol.EscapeMeasurement([]byte(m.name)))
+		for _, t := range m.tags {
+			series += "," + string(lineprotocol.EscapeTag([]byte(t.key))) + "="
+			if len(t.seen) > maxSampleValues {
+				series += "#" + strconv.Itoa(len(t.seen))
+				continue
+			}
+
+			values := make([]string, 0, len(t.values))
+			for _, v := range t.values {
+				v = string(lineprotocol.EscapeTag([]byte(v)))
+				values = append(values, strings.NewReplacer("{", `\{`, "}", `\}`).Replace(v))
+			}
+			series += "{" + strings.Join(values, ",") + "}"
+		}
+	}
+
+	fields := make([]string, 0, len(m.fields))
+	for _, f := range m.fields {
+		field := string(lineprotocol.EscapeFieldKey([]byte(f.key))) + "=" + f.value()
+		if f.count < m.points {
+			p := math.Max(0.01, math.Round(float64(f.count)/float64(m.points)*100)/100)
+			field += "?" + formatFloat(p)
+		}
+		fields = append(fields, field)
+	}
+
+	return Template{
+		Series:  series,
+		Fields:  strings.Join(fields, ","),
+		SeriesN: len(m.seen),
+		Weight:  float64(m.points),
+	}
+}
+
+// value returns the FIELDS template value of the field.
+func (f *sampleField) value() string {
+	switch f.typ {
+	case Boolean:
+		return "t"
+	case String:
+		return `"` + string(lineprotocol.EscapeString([]byte(f.example))) + `"`
+	}
+
+	v := "uniform(" + formatFloat(f.min) + "," + formatFloat(f.max) + ")"
+	if f.min == f.max {
+		v = "constant(" + formatFloat(f.min) + ")"
+	}
+
+	switch f.typ {
+	case Integer:
+		return v + "i"
+	case Unsigned:
+		return v + "u"
+	}
+	return v
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,115 @@
+package point_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+	"github.com/influxdata/influx-stress/point"
+)
+
+const sample = `# A dump of a few points
+cpu,host=a,cpu=cpu0 usage_user=1.5,usage_idle=90 1
+cpu,host=b,cpu=cpu0 usage_user=3,usage_idle=90 2
+cpu,host=a,cpu=cpu1 usage_user=2,usage_idle=90,guest=0i 3
+disk,host=a,path=/var/lib\ docker,dc={east} free=10u,ok=true,mode="rw,noatime" 4
+cpu,host=c,cpu=cpu1 usage_user=0.5,usage_idle=90 5
+`
+
+func TestInferTemplates(t *testing.T) {
+	templates, err := point.InferTemplates(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []point.Template{
+		{
+			Series:  "{cpu,host=a,cpu=cpu0 cpu,host=b,cpu=cpu0 cpu,host=a,cpu=cpu1 cpu,host=c,cpu=cpu1}",
+			Fields:  "usage_user=uniform(0.5,3),usage_idle=constant(90),guest=constant(0)i?0.25",
+			SeriesN: 4,
+			Weight:  4,
+		},
+		{
+			Series:  `{disk,host=a,path=/var/lib\ docker,dc=\{east\}}`,
+			Fields:  `free=constant(10)u,ok=t,mode="rw,noatime"`,
+			SeriesN: 1,
+			Weight:  1,
+		},
+	}
+
+	if !reflect.DeepEqual(templates, exp) {
+		t.Fatalf("Wrong templates inferred.\ngot %v\nexp %v", templates, exp)
+	}
+
+	// The templates are printed in a syntax that parses back to them.
+	for _, tmpl := range templates {
+		got, err := point.ParseTemplate(tmpl.String(), "", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tmpl) {
+			t.Errorf("Wrong template parsed from %q. got %+v, exp %+v", tmpl.String(), got, tmpl)
+		}
+	}
+
+	// The templates generate points with the sample's series keys.
+	pts, err := templates[0].NewPoints(point.Config{Precision: lineprotocol.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(pts), 4; got != exp {
+		t.Errorf("Wrong number of series generated. got %v, exp %v", got, exp)
+	}
+
+	pts, err = templates[1].NewPoints(point.Config{Precision: lineprotocol.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	pts[0].SetTime(testTime)
+	if err := lineprotocol.WritePoint(buf, pts[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := buf.String(), `disk,host=a,path=/var/lib\ docker,dc={east} `; !strings.HasPrefix(got, exp) {
+		t.Errorf("Wrong series generated. got %v, exp %v", got, exp)
+	}
+}
+
+func TestInferTemplates_ManySeries(t *testing.T) {
+	sample := bytes.NewBuffer(nil)
+	for i := 0; i < 1500; i++ {
+		for j := 0; j < 4; j++ {
+			fmt.Fprintf(sample, "cpu,host=server-%d,cpu=cpu%d usage=1\n", i, j)
+		}
+	}
+
+	templates, err := point.InferTemplates(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tags keep the cardinality seen, and the values of the tags with few of them.
+	exp := []point.Template{{Series: "cpu,host=#1500,cpu={cpu0,cpu1,cpu2,cpu3}", Fields: "usage=constant(1)", SeriesN: 6000, Weight: 6000}}
+	if !reflect.DeepEqual(templates, exp) {
+		t.Fatalf("Wrong templates inferred.\ngot %v\nexp %v", templates, exp)
+	}
+
+	pts, err := templates[0].NewPoints(point.Config{Precision: lineprotocol.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(pts), 6000; got != exp {
+		t.Errorf("Wrong number of series generated. got %v, exp %v", got, exp)
+	}
+}
+
+func TestInferTemplates_invalid(t *testing.T) {
+	if _, err := point.InferTemplates(strings.NewReader("cpu value=\n")); err == nil {
+		t.Error("Expected an error inferring templates from invalid line protocol")
+	}
+}
@@ -2,9 +2,13 @@ package point
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
 )
 
 func primeFactorization(n int) (factors map[int]int) {
@@ -31,6 +35,9 @@ func primeFactorization(n int) (factors map[int]int) {
 
 func tagCardinalityPartition(numTags int, factors map[int]int) []int {
 	buckets := make([]int, numTags)
+	if numTags == 0 {
+		return buckets
+	}
 
 	for i := range buckets {
 		buckets[i] = 1
@@ -52,39 +59,332 @@ func tagCardinalityPartition(numTags int, factors map[int]int) []int {
 	return buckets
 }
 
-func generateSeriesKeys(tmplt string, card int) [][]byte {
-	fmtTmplt, numTags := formatTemplate(tmplt)
-	tagCardinalities := tagCardinalityPartition(numTags, primeFactorization(card))
+// tagTemplate is a tag of a SERIES template. The key and value are unescaped.
+type tagTemplate struct {
+	key, value string
 
-	series := [][]byte{}
+	// card is the number of values of the tag, given with `key=#card` or
+	// by the length of values. It is 0 if the tag shares the --series count
+	// with the other tags.
+	card int
+
+	// values, if not empty, are the values of the tag given with
+	// `key={a,b,c}` or `key=@file`.
+	values []string
+}
+
+// seriesTemplate is a parsed SERIES template such as `cpu,host=server`.
+type seriesTemplate struct {
+	measurement string
+	tags        []tagTemplate
+
+	// keys, if not empty, are the escaped series keys given with
+	// `{cpu,host=a cpu,host=b}`, which are generated as is.
+	keys [][]byte
+}
+
+// parseSeriesTemplate parses a SERIES template, which is written like a line
+// protocol series key, so commas, equals signs and spaces may be escaped
+// with a backslash. A tag written as `key=#N` has exactly N values, one
+// written as `key={a,b,c}` has the values listed, and one written as
+// `key=@file` has the values in the file, one per line. A SERIES template
+// written as `{cpu,host=a cpu,host=b}` lists the series keys to write.
+func parseSeriesTemplate(s string) (seriesTemplate, error) {
+	if strings.HasPrefix(s, "{") {
+		return parseSeriesList(s)
+	}
+
+	parts := splitTags(s)
+
+	t := seriesTemplate{
+		measurement: string(lineprotocol.Unescape([]byte(parts[0]))),
+	}
+	if t.measurement == "" {
+		return t, fmt.Errorf("missing measurement in series %q", s)
+	}
+
+	for _, part := range parts[1:] {
+		i := indexUnescaped(part, '=')
+		if i <= 0 {
+			return t, fmt.Errorf("invalid tag %q in series %q: expected key=value", part, s)
+		}
+
+		tag := tagTemplate{
+			key:   string(lineprotocol.Unescape([]byte(part[:i]))),
+			value: string(lineprotocol.Unescape([]byte(part[i+1:]))),
+		}
+
+		var err error
+		switch raw := part[i+1:]; {
+		case strings.HasPrefix(raw, "#"):
+			n, err := strconv.Atoi(raw[1:])
+			if err != nil || n < 1 {
+				return t, fmt.Errorf("invalid cardinality %q for tag %q", raw, tag.key)
+			}
+			tag.card = n
+		case strings.HasPrefix(raw, "{"):
+			if !strings.HasSuffix(raw, "}") {
+				return t, fmt.Errorf("unterminated list of values %q for tag %q", raw, tag.key)
+			}
+			tag.values = parseTagValues(raw[1 : len(raw)-1])
+		case strings.HasPrefix(raw, "@"):
+			tag.values, err = readTagValues(raw[1:])
+			if err != nil {
+				return t, fmt.Errorf("reading values for tag %q: %v", tag.key, err)
+			}
+		}
+
+		if tag.values != nil {
+			if len(tag.values) == 0 {
+				return t, fmt.Errorf("no values for tag %q", tag.key)
+			}
+			tag.card = len(tag.values)
+		}
+
+		t.tags = append(t.tags, tag)
+	}
+
+	return t, nil
+}
 
-	for i := 0; i < card; i++ {
-		mods := sliceMod(i, tagCardinalities)
-		series = append(series, []byte(fmt.Sprintf(fmtTmplt, mods...)))
+// parseSeriesList parses a list of series keys such as `{cpu,host=a cpu,host=b}`.
+// The keys are separated by spaces and must have the same measurement. Braces
+// in the keys are escaped with a backslash.
+func parseSeriesList(s string) (seriesTemplate, error) {
+	t := seriesTemplate{}
+	if !strings.HasSuffix(s, "}") {
+		return t, fmt.Errorf("unterminated list of series %q", s)
+	}
+
+	keys := []string{}
+	for _, key := range splitUnescaped(s[1:len(s)-1], ' ') {
+		if key != "" {
+			keys = append(keys, strings.NewReplacer(`\{`, "{", `\}`, "}").Replace(key))
+		}
 	}
+	keys = uniqueValues(keys)
+	if len(keys) == 0 {
+		return t, fmt.Errorf("no series in %q", s)
+	}
+
+	for _, key := range keys {
+		parts := splitUnescaped(key, ',')
+		measurement := string(lineprotocol.Unescape([]byte(parts[0])))
+		if measurement == "" {
+			return t, fmt.Errorf("missing measurement in series %q", key)
+		}
+		if t.keys != nil && measurement != t.measurement {
+			return t, fmt.Errorf("series %q does not have the measurement %q", key, t.measurement)
+		}
+		for _, part := range parts[1:] {
+			if i := indexUnescaped(part, '='); i <= 0 || i == len(part)-1 {
+				return t, fmt.Errorf("invalid tag %q in series %q: expected key=value", part, key)
+			}
+		}
 
-	return series
+		t.measurement = measurement
+		t.keys = append(t.keys, []byte(key))
+	}
+
+	return t, nil
+}
+
+// parseTagValues returns the values in a list such as `a,b,c`. Commas and
+// braces in the values may be escaped with a backslash.
+func parseTagValues(s string) []string {
+	values := []string{}
+	for _, v := range splitUnescaped(s, ',') {
+		v = strings.NewReplacer(`\,`, ",", `\{`, "{", `\}`, "}").Replace(v)
+		values = append(values, string(lineprotocol.Unescape([]byte(v))))
+	}
+
+	return uniqueValues(values)
 }
 
-func formatTemplate(s string) (string, int) {
-	parts := strings.Split(s, ",")
-	numTags := len(parts) - 1
+// readTagValues returns the lines of the file at path, skipping empty lines.
+func readTagValues(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+
+	return uniqueValues(values), nil
+}
 
-	for i, part := range parts {
-		if i == 0 {
+// uniqueValues removes repeated values, so that no series is generated twice.
+func uniqueValues(values []string) []string {
+	seen := map[string]bool{}
+	unique := values[:0]
+	for _, v := range values {
+		if v == "" || seen[v] {
 			continue
 		}
-		parts[i] = part + "-%v"
+		seen[v] = true
+		unique = append(unique, v)
+	}
+
+	return unique
+}
+
+// seriesKey returns the escaped series key with the n-th value of each tag,
+// which is the template value with the suffix "-n", or for a tag with an
+// explicit cardinality the tag key with the suffix "-n", or for a tag with
+// a list of values the n-th one.
+func (t seriesTemplate) seriesKey(ns []int) []byte {
+	sk := append([]byte{}, lineprotocol.EscapeMeasurement([]byte(t.measurement))...)
+
+	for i, tag := range t.tags {
+		sk = append(sk, ',')
+		sk = append(sk, lineprotocol.EscapeTag([]byte(tag.key))...)
+		sk = append(sk, '=')
+		var value string
+		switch {
+		case len(tag.values) > 0:
+			value = tag.values[ns[i]]
+		case tag.card > 0:
+			value = tag.key + "-" + strconv.Itoa(ns[i])
+		default:
+			value = tag.value + "-" + strconv.Itoa(ns[i])
+		}
+		sk = append(sk, lineprotocol.EscapeTag([]byte(value))...)
+	}
+
+	return sk
+}
+
+// tagCardinalities returns the number of values of each tag. Tags with an
+// explicit cardinality keep it, and the others split what is left of card
+// once the explicit cardinalities are accounted for, rounded down to a whole
+// number of at least 1, so the product is not card if it is not a multiple of
+// the explicit cardinalities.
+func (t seriesTemplate) tagCardinalities(card int) []int {
+	explicit := 1
+	implicitN := 0
+	for _, tag := range t.tags {
+		if tag.card > 0 {
+			explicit *= tag.card
+		} else {
+			implicitN++
+		}
+	}
+
+	shared := card / explicit
+	if shared < 1 {
+		shared = 1
+	}
+	partition := tagCardinalityPartition(implicitN, primeFactorization(shared))
+
+	cards := make([]int, 0, len(t.tags))
+	for _, tag := range t.tags {
+		if tag.card > 0 {
+			cards = append(cards, tag.card)
+		} else {
+			cards = append(cards, partition[0])
+			partition = partition[1:]
+		}
+	}
+
+	return cards
+}
+
+// generateSeriesKeys returns the series keys for a SERIES template. Without
+// explicit tag cardinalities there are card series, split between the tags.
+// Otherwise the number of series is the product of the tag cardinalities.
+// The series are the nested cartesian product of the tag values, with the
+// first tag varying slowest. A list of series keys is generated as is.
+func generateSeriesKeys(tmplt string, card int) ([][]byte, error) {
+	t, err := parseSeriesTemplate(tmplt)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.keys) > 0 {
+		return t.keys, nil
+	}
+
+	series := [][]byte{}
+	tagCardinalities := t.tagCardinalities(card)
+	total := 1
+	for _, c := range tagCardinalities {
+		total *= c
+	}
+
+	for i := 0; i < total; i++ {
+		series = append(series, t.seriesKey(nestedIndexes(i, tagCardinalities)))
+	}
+
+	return series, nil
+}
+
+// splitTags splits a SERIES template on the commas that are not escaped
+// with a backslash or inside a list of tag values.
+func splitTags(s string) []string {
+	parts := []string{}
+
+	start := 0
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// splitUnescaped splits s on each sep that is not escaped with a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	parts := []string{}
+
+	for {
+		i := indexUnescaped(s, sep)
+		if i == -1 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// indexUnescaped returns the index of the first c in s that is not escaped
+// with a backslash, or -1.
+func indexUnescaped(s string, c byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case c:
+			return i
+		}
 	}
 
-	return strings.Join(parts, ","), numTags
+	return -1
 }
 
-func sliceMod(m int, mods []int) []interface{} {
-	ms := []interface{}{}
-	for _, mod := range mods {
-		ms = append(ms, m%mod)
+// nestedIndexes returns the index of each tag's value in the m-th element
+// of the nested cartesian product of tags with the given cardinalities.
+func nestedIndexes(m int, cards []int) []int {
+	ns := make([]int, len(cards))
+	for i := len(cards) - 1; i >= 0; i-- {
+		ns[i] = m % cards[i]
+		m /= cards[i]
 	}
 
-	return ms
+	return ns
 }
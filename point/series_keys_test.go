@@ -1,7 +1,10 @@
 package point
 
 import (
+	"io/ioutil"
 	"math"
+	"os"
+	"reflect"
 	"testing"
 )
 
@@ -85,3 +88,161 @@ func TestTagCardinalityPartition(t *testing.T) {
 	}
 
 }
+
+func TestGenerateSeriesKeys_Escaped(t *testing.T) {
+	series, err := generateSeriesKeys(`disk\ io,path=/var/lib\ docker,a\,b=c\=d`, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := string(series[0]), `disk\ io,path=/var/lib\ docker-0,a\,b=c\=d-0`; got != exp {
+		t.Errorf("Wrong series key generated. Got %v Expected %v\n", got, exp)
+	}
+}
+
+func TestGenerateSeriesKeys_Invalid(t *testing.T) {
+	for _, s := range []string{"", ",host=a", "cpu,host", "cpu,=a", "cpu,host=#0", "cpu,host=#x", "cpu,host={}", "cpu,host={a,b", "{}", "{cpu", "{cpu,host=a mem,host=b}", "{cpu,host}"} {
+		if _, err := generateSeriesKeys(s, 1); err == nil {
+			t.Errorf("Expected an error generating series from %q", s)
+		}
+	}
+}
+
+func TestGenerateSeriesKeys_ExplicitCardinality(t *testing.T) {
+	series, err := generateSeriesKeys("cpu,region=#2,host=#3,cpu=#2", 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := len(series), 12; got != exp {
+		t.Fatalf("Wrong number of series generated. Got %v Expected %v\n", got, exp)
+	}
+
+	exp := []string{
+		"cpu,region=region-0,host=host-0,cpu=cpu-0",
+		"cpu,region=region-0,host=host-0,cpu=cpu-1",
+		"cpu,region=region-0,host=host-1,cpu=cpu-0",
+	}
+	for i, sk := range exp {
+		if got := string(series[i]); got != sk {
+			t.Errorf("Wrong series key generated. Got %v Expected %v\n", got, sk)
+		}
+	}
+
+	if got, exp := string(series[11]), "cpu,region=region-1,host=host-2,cpu=cpu-1"; got != exp {
+		t.Errorf("Wrong series key generated. Got %v Expected %v\n", got, exp)
+	}
+}
+
+func TestGenerateSeriesKeys_MixedCardinality(t *testing.T) {
+	series, err := generateSeriesKeys("cpu,region=#5,host=server", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := len(series), 100; got != exp {
+		t.Fatalf("Wrong number of series generated. Got %v Expected %v\n", got, exp)
+	}
+
+	unique := map[string]bool{}
+	for _, sk := range series {
+		unique[string(sk)] = true
+	}
+	if got, exp := len(unique), 100; got != exp {
+		t.Errorf("Wrong number of unique series generated. Got %v Expected %v\n", got, exp)
+	}
+
+	if got, exp := string(series[99]), "cpu,region=region-4,host=server-19"; got != exp {
+		t.Errorf("Wrong series key generated. Got %v Expected %v\n", got, exp)
+	}
+}
+
+func TestGenerateSeriesKeys_PrimeSeriesCount(t *testing.T) {
+	series, err := generateSeriesKeys("cpu,host=server,region=us", 53)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unique := map[string]bool{}
+	for _, sk := range series {
+		unique[string(sk)] = true
+	}
+	if got, exp := len(unique), 53; got != exp {
+		t.Errorf("Wrong number of unique series generated. Got %v Expected %v\n", got, exp)
+	}
+}
+
+func TestGenerateSeriesKeys_TagValueList(t *testing.T) {
+	series, err := generateSeriesKeys(`cpu,region={us-east,us-west,eu},host=#2,path={/var/lib\ docker,a\,b}`, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := len(series), 12; got != exp {
+		t.Fatalf("Wrong number of series generated. Got %v Expected %v\n", got, exp)
+	}
+
+	exp := []string{
+		`cpu,region=us-east,host=host-0,path=/var/lib\ docker`,
+		`cpu,region=us-east,host=host-0,path=a\,b`,
+		`cpu,region=us-east,host=host-1,path=/var/lib\ docker`,
+	}
+	for i, sk := range exp {
+		if got := string(series[i]); got != sk {
+			t.Errorf("Wrong series key generated. Got %v Expected %v\n", got, sk)
+		}
+	}
+
+	if got, exp := string(series[11]), `cpu,region=eu,host=host-1,path=a\,b`; got != exp {
+		t.Errorf("Wrong series key generated. Got %v Expected %v\n", got, exp)
+	}
+}
+
+func TestGenerateSeriesKeys_TagValueFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "tag-values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("web-01\nweb-02\n\nweb-01\ndb 01\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	series, err := generateSeriesKeys("cpu,host=@"+f.Name(), 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, sk := range series {
+		got = append(got, string(sk))
+	}
+
+	exp := []string{"cpu,host=web-01", "cpu,host=web-02", `cpu,host=db\ 01`}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong series keys generated. Got %v Expected %v\n", got, exp)
+	}
+
+	if _, err := generateSeriesKeys("cpu,host=@"+f.Name()+".missing", 1); err == nil {
+		t.Errorf("Expected an error reading tag values from a missing file")
+	}
+}
+
+func TestGenerateSeriesKeys_SeriesList(t *testing.T) {
+	series, err := generateSeriesKeys(`{cpu,host=a,cpu=cpu0 cpu,host=b  cpu,host=a,cpu=cpu0 cpu,path=/var/lib\ docker,dc=\{east\}}`, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, sk := range series {
+		got = append(got, string(sk))
+	}
+
+	exp := []string{"cpu,host=a,cpu=cpu0", "cpu,host=b", `cpu,path=/var/lib\ docker,dc={east}`}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong series keys generated. Got %v Expected %v\n", got, exp)
+	}
+}
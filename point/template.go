@@ -0,0 +1,146 @@
+package point
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+)
+
+// Template describes one measurement of a workload: the SERIES and FIELDS
+// templates its points are generated from, the number of series shared by
+// the tags without an explicit cardinality, and its share of the writes
+// relative to the other measurements.
+type Template struct {
+	Series  string
+	Fields  string
+	SeriesN int
+	Weight  float64
+}
+
+// ParseTemplate parses a measurement written as `SERIES [FIELDS [series=N] [weight=W]]`,
+// for example `cpu,host=#50 usage=randwalk(0,100,1) weight=60`. Spaces may be
+// escaped with a backslash, and may appear in string field values. The fields
+// default to fields, the series to seriesN and the weight to 1. The options
+// follow the FIELDS, which may themselves start with series= or weight=.
+func ParseTemplate(s, fields string, seriesN int) (Template, error) {
+	t := Template{
+		Fields:  fields,
+		SeriesN: seriesN,
+		Weight:  1,
+	}
+
+	parts, err := splitTemplate(s)
+	if err != nil {
+		return t, err
+	}
+	if len(parts) == 0 {
+		return t, fmt.Errorf("empty measurement template")
+	}
+
+	t.Series, parts = parts[0], parts[1:]
+	if len(parts) > 0 {
+		t.Fields, parts = parts[0], parts[1:]
+	}
+	for _, part := range parts {
+		switch {
+		case strings.HasPrefix(part, "series="):
+			t.SeriesN, err = strconv.Atoi(strings.TrimPrefix(part, "series="))
+			if err != nil || t.SeriesN < 1 {
+				return t, fmt.Errorf("invalid series count %q in %q", part, s)
+			}
+		case strings.HasPrefix(part, "weight="):
+			t.Weight, err = strconv.ParseFloat(strings.TrimPrefix(part, "weight="), 64)
+			if err != nil || t.Weight <= 0 {
+				return t, fmt.Errorf("invalid weight %q in %q", part, s)
+			}
+		default:
+			return t, fmt.Errorf("unexpected %q in %q", part, s)
+		}
+	}
+
+	if _, err := parseSeriesTemplate(t.Series); err != nil {
+		return t, err
+	}
+	if _, err := generateFieldSet(t.Fields); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// Measurement returns the unescaped measurement name of the template.
+func (t Template) Measurement() string {
+	st, err := parseSeriesTemplate(t.Series)
+	if err != nil {
+		return t.Series
+	}
+	return st.measurement
+}
+
+// SharesSeriesN reports whether the SeriesN of the template is split between
+// tags without an explicit cardinality. Otherwise it is not used.
+func (t Template) SharesSeriesN() bool {
+	st, err := parseSeriesTemplate(t.Series)
+	if err != nil || len(st.keys) > 0 {
+		return false
+	}
+
+	for _, tag := range st.tags {
+		if tag.card == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the template written as `SERIES FIELDS series=N weight=W`,
+// which ParseTemplate parses back.
+func (t Template) String() string {
+	return t.Series + " " + t.Fields + " series=" + strconv.Itoa(t.SeriesN) + " weight=" + formatFloat(t.Weight)
+}
+
+// NewPoints returns the points of the template, see NewPoints.
+func (t Template) NewPoints(cfg Config) ([]lineprotocol.Point, error) {
+	return NewPoints(t.Series, t.Fields, t.SeriesN, cfg)
+}
+
+// splitTemplate splits a measurement template on the spaces that are not
+// escaped or inside a string value, a generator or a list of tag values.
+func splitTemplate(s string) ([]string, error) {
+	parts := []string{}
+
+	start := 0
+	quoted := false
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '{':
+			depth++
+		case c == ')' || c == '}':
+			depth--
+		case c == ' ' && depth == 0:
+			if i > start {
+				parts = append(parts, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated string in %q", s)
+	}
+
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,91 @@
+package point_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influx-stress/point"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := map[string]point.Template{
+		"cpu,host=#50": {
+			Series: "cpu,host=#50", Fields: "n=0i", SeriesN: 100, Weight: 1,
+		},
+		`cpu,host=#50 usage=randwalk(0, 100, 1),msg="a b" weight=60`: {
+			Series: "cpu,host=#50", Fields: `usage=randwalk(0, 100, 1),msg="a b"`, SeriesN: 100, Weight: 60,
+		},
+		`disk,path=/var/lib\ docker,dc={us east,eu}  used=0  series=20 weight=0.5`: {
+			Series: `disk,path=/var/lib\ docker,dc={us east,eu}`, Fields: "used=0", SeriesN: 20, Weight: 0.5,
+		},
+		// The FIELDS come before the options, even named like them.
+		"cpu series=1i,weight=2": {
+			Series: "cpu", Fields: "series=1i,weight=2", SeriesN: 100, Weight: 1,
+		},
+		"cpu weight=3 series=5": {
+			Series: "cpu", Fields: "weight=3", SeriesN: 5, Weight: 1,
+		},
+	}
+
+	for s, exp := range tests {
+		got, err := point.ParseTemplate(s, "n=0i", 100)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", s, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("Wrong template parsed from %q. got %+v, exp %+v", s, got, exp)
+		}
+	}
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	for _, s := range []string{"", "cpu,host a=1", "cpu a=x", "cpu a=1 weight=0", "cpu a=1 series=x", "cpu a=1 b=2", `cpu a="b`} {
+		if _, err := point.ParseTemplate(s, "n=0i", 100); err == nil {
+			t.Errorf("Expected an error parsing %q", s)
+		}
+	}
+}
+
+func TestTemplate_Measurement(t *testing.T) {
+	tmpl := point.Template{Series: `disk\ io,path=/var`}
+	if got, exp := tmpl.Measurement(), "disk io"; got != exp {
+		t.Errorf("Wrong measurement. got %v, exp %v", got, exp)
+	}
+}
+
+func TestTemplate_SharesSeriesN(t *testing.T) {
+	tests := map[string]bool{
+		"cpu":                     false,
+		"cpu,host=server":         true,
+		"cpu,host=#50":            false,
+		"cpu,host=#50,core=c":     true,
+		"cpu,dc={us,eu},host=#3":  false,
+		"{cpu,host=a cpu,host=b}": false,
+	}
+
+	for series, exp := range tests {
+		if got := (point.Template{Series: series}).SharesSeriesN(); got != exp {
+			t.Errorf("Wrong SharesSeriesN for %q. got %v, exp %v", series, got, exp)
+		}
+	}
+}
+
+func TestTemplate_String(t *testing.T) {
+	tmpl := point.Template{Series: `{cpu,host=a cpu,host=b\ c}`, Fields: `usage=0,msg="a b"`, SeriesN: 2, Weight: 0.5}
+	if got, exp := tmpl.String(), `{cpu,host=a cpu,host=b\ c} usage=0,msg="a b" series=2 weight=0.5`; got != exp {
+		t.Errorf("Wrong template string. got %v, exp %v", got, exp)
+	}
+
+	got, err := point.ParseTemplate(tmpl.String(), "n=0i", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tmpl) {
+		t.Errorf("Wrong template parsed from %q. got %+v, exp %+v", tmpl.String(), got, tmpl)
+	}
+	if got, exp := got.Measurement(), "cpu"; got != exp {
+		t.Errorf("Wrong measurement. got %v, exp %v", got, exp)
+	}
+}
@@ -0,0 +1,255 @@
+package stress
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Selector picks which of a writer's points is written next.
+type Selector interface {
+	// Next returns the index of the next point to write.
+	Next() int
+}
+
+// NewSelector returns a Selector over n points for a distribution, which is one of
+//
+//	uniform    every point in turn
+//	zipf(s)    a Zipf distribution with exponent s > 1, the first points being the hottest
+//	hot(x,y)   x% of the writes go to the first y% of the points
+//
+// The random distributions draw from r.
+func NewSelector(dist string, n int, r *rand.Rand) (Selector, error) {
+	return NewInterleavedSelector(dist, n, 0, 1, n, r)
+}
+
+// NewInterleavedSelector returns a Selector over the n points of ranks offset,
+// offset+stride, offset+2*stride... of total points, the hottest being of rank
+// 0, for a distribution over the total points as in NewSelector. Writers given
+// interleaved points this way together follow the distribution, except that
+// the writes of each writer are spread over its points with the probabilities
+// they have in the distribution, so a point hotter than a writer's share of
+// the writes gets that share.
+func NewInterleavedSelector(dist string, n, offset, stride, total int, r *rand.Rand) (Selector, error) {
+	name, args, err := parseDistribution(dist)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "uniform":
+		if len(args) != 0 {
+			break
+		}
+		return &roundRobin{n: n}, nil
+	case "zipf":
+		if len(args) != 1 {
+			break
+		}
+		if args[0] <= 1 {
+			return nil, fmt.Errorf("invalid distribution %q: exponent must be greater than 1", dist)
+		}
+		if stride == 1 && offset == 0 {
+			return &zipf{z: rand.NewZipf(r, args[0], 1, uint64(n-1))}, nil
+		}
+
+		// The probability of the point of rank k is proportional to (1+k)^-s,
+		// as with rand.Zipf.
+		cum := make([]float64, n)
+		sum := 0.0
+		for i := range cum {
+			sum += math.Pow(float64(1+offset+i*stride), -args[0])
+			cum[i] = sum
+		}
+		return &cumulative{cum: cum, r: r}, nil
+	case "hot":
+		if len(args) != 2 {
+			break
+		}
+		if args[0] < 0 || args[0] > 100 || args[1] <= 0 || args[1] > 100 {
+			return nil, fmt.Errorf("invalid distribution %q: percentages must be between 0 and 100", dist)
+		}
+		hotN := int(float64(total) * args[1] / 100)
+		if hotN < 1 {
+			hotN = 1
+		}
+
+		// The hot points of the writer are its points of rank below hotN.
+		hotN = (hotN - offset + stride - 1) / stride
+		if hotN < 0 {
+			hotN = 0
+		}
+		if hotN > n {
+			hotN = n
+		}
+		return &hotSet{n: n, hotN: hotN, hotP: args[0] / 100, r: r}, nil
+	default:
+		return nil, fmt.Errorf("unknown distribution %q", name)
+	}
+
+	return nil, fmt.Errorf("wrong number of arguments to distribution %q", dist)
+}
+
+// parseDistribution splits a distribution such as `hot(80,20)` into its name and arguments.
+func parseDistribution(dist string) (string, []float64, error) {
+	open := strings.IndexByte(dist, '(')
+	if open == -1 {
+		return dist, nil, nil
+	}
+
+	if !strings.HasSuffix(dist, ")") {
+		return "", nil, fmt.Errorf("invalid distribution %q", dist)
+	}
+
+	args := []float64{}
+	if argStr := strings.TrimSpace(dist[open+1 : len(dist)-1]); argStr != "" {
+		for _, a := range strings.Split(argStr, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid argument %q in distribution %q", a, dist)
+			}
+			args = append(args, v)
+		}
+	}
+
+	return dist[:open], args, nil
+}
+
+type roundRobin struct {
+	n, i int
+}
+
+func (s *roundRobin) Next() int {
+	i := s.i
+	s.i = (s.i + 1) % s.n
+	return i
+}
+
+type zipf struct {
+	z *rand.Zipf
+}
+
+func (s *zipf) Next() int {
+	return int(s.z.Uint64())
+}
+
+type hotSet struct {
+	n, hotN int
+	hotP    float64
+	r       *rand.Rand
+}
+
+func (s *hotSet) Next() int {
+	if s.hotN == s.n || s.hotN > 0 && s.r.Float64() < s.hotP {
+		return s.r.Intn(s.hotN)
+	}
+	return s.hotN + s.r.Intn(s.n-s.hotN)
+}
+
+// cumulative picks the point i with a probability proportional to
+// cum[i] - cum[i-1].
+type cumulative struct {
+	cum []float64
+	r   *rand.Rand
+}
+
+func (s *cumulative) Next() int {
+	i := sort.SearchFloat64s(s.cum, s.r.Float64()*s.cum[len(s.cum)-1])
+	if i == len(s.cum) {
+		i--
+	}
+	return i
+}
+
+// onceEach picks each of n points exactly once in every n picks. The order
+// follows sel: a pick of a point already written since the last reset is
+// replaced by the next point not written yet, so that weights and
+// distributions still decide which points come first.
+type onceEach struct {
+	sel Selector
+
+	// next[i] leads to the first point not written yet at or after i, or
+	// to n if there is none, following the chain of next.
+	next []int
+	left int
+}
+
+func newOnceEach(sel Selector, n int) *onceEach {
+	s := &onceEach{sel: sel, next: make([]int, n+1)}
+	s.reset()
+	return s
+}
+
+func (s *onceEach) reset() {
+	for i := range s.next {
+		s.next[i] = i
+	}
+	s.left = len(s.next) - 1
+}
+
+// find returns the first point not written yet at or after i, or n.
+func (s *onceEach) find(i int) int {
+	for s.next[i] != i {
+		s.next[i] = s.next[s.next[i]]
+		i = s.next[i]
+	}
+	return i
+}
+
+func (s *onceEach) Next() int {
+	if s.left == 0 {
+		s.reset()
+	}
+
+	i := s.find(s.sel.Next())
+	if i == len(s.next)-1 {
+		i = s.find(0)
+	}
+	s.next[i] = i + 1
+	s.left--
+	return i
+}
+
+// A Group is a contiguous range of a writer's points, starting at Offset,
+// with its own Selector and a weight relative to the other groups.
+type Group struct {
+	Offset   int
+	Selector Selector
+	Weight   float64
+}
+
+// NewWeightedSelector returns a Selector that interleaves the groups in
+// proportion to their weights, and picks a point within the chosen group
+// with that group's Selector.
+func NewWeightedSelector(groups []Group) Selector {
+	return &weighted{
+		groups:  groups,
+		current: make([]float64, len(groups)),
+	}
+}
+
+// weighted is a smooth weighted round robin over the groups, which spreads
+// each group's writes evenly rather than in runs.
+type weighted struct {
+	groups  []Group
+	current []float64
+}
+
+func (s *weighted) Next() int {
+	total := 0.0
+	best := 0
+	for i, g := range s.groups {
+		s.current[i] += g.Weight
+		total += g.Weight
+		if s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	s.current[best] -= total
+
+	g := s.groups[best]
+	return g.Offset + g.Selector.Next()
+}
@@ -0,0 +1,99 @@
+package stress_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influx-stress/stress"
+)
+
+func picks(t *testing.T, dist string, n, times int) []int {
+	sel, err := stress.NewSelector(dist, n, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Unexpected error for distribution %v: %v", dist, err)
+	}
+
+	counts := make([]int, n)
+	for i := 0; i < times; i++ {
+		counts[sel.Next()]++
+	}
+
+	return counts
+}
+
+func TestNewSelector_Uniform(t *testing.T) {
+	if got, exp := picks(t, "uniform", 4, 12), []int{3, 3, 3, 3}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong uniform counts. got %v, exp %v", got, exp)
+	}
+}
+
+func TestNewSelector_Zipf(t *testing.T) {
+	counts := picks(t, "zipf(1.5)", 100, 100000)
+
+	for i := 1; i < 10; i++ {
+		if counts[i] > counts[i-1] {
+			t.Errorf("Zipf counts are not decreasing: %v", counts[:10])
+			break
+		}
+	}
+
+	if counts[0] < 100000/4 {
+		t.Errorf("Hottest series was not hot enough: %v", counts[0])
+	}
+}
+
+func TestNewSelector_Hot(t *testing.T) {
+	counts := picks(t, "hot(80,20)", 100, 100000)
+
+	hot := 0
+	for _, c := range counts[:20] {
+		hot += c
+	}
+
+	if share := float64(hot) / 100000; share < 0.78 || share > 0.82 {
+		t.Errorf("Wrong share of writes to the hot set. got %v, exp %v", share, 0.8)
+	}
+}
+
+func TestNewSelector_SinglePoint(t *testing.T) {
+	for _, dist := range []string{"uniform", "zipf(2)", "hot(90,10)"} {
+		if got, exp := picks(t, dist, 1, 10), []int{10}; !reflect.DeepEqual(got, exp) {
+			t.Errorf("Wrong counts for %v. got %v, exp %v", dist, got, exp)
+		}
+	}
+}
+
+func TestNewSelector_Invalid(t *testing.T) {
+	for _, dist := range []string{"", "normal", "uniform(1)", "zipf", "zipf(1)", "zipf(x)", "hot(80)", "hot(120,20)", "hot(80,0)", "zipf(2"} {
+		if _, err := stress.NewSelector(dist, 10, rand.New(rand.NewSource(1))); err == nil {
+			t.Errorf("Expected an error for distribution %q", dist)
+		}
+	}
+}
+
+func TestNewWeightedSelector(t *testing.T) {
+	groups := []stress.Group{}
+	offset := 0
+	for _, g := range []struct {
+		n      int
+		weight float64
+	}{{2, 6}, {3, 3}, {1, 1}} {
+		sel, err := stress.NewSelector("uniform", g.n, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups = append(groups, stress.Group{Offset: offset, Selector: sel, Weight: g.weight})
+		offset += g.n
+	}
+
+	sel := stress.NewWeightedSelector(groups)
+	counts := make([]int, offset)
+	for i := 0; i < 100; i++ {
+		counts[sel.Next()]++
+	}
+
+	if got, exp := counts, []int{30, 30, 10, 10, 10, 10}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong weighted counts. got %v, exp %v", got, exp)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
+	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influx-stress/lineprotocol"
@@ -29,11 +31,150 @@ type WriteConfig struct {
 	// Otherwise, pass this value to the gzip writer.
 	GzipLevel int
 
+	// Precision is the precision the points' timestamps are written in.
+	// A point is never given a timestamp that falls in the same unit of
+	// Precision as its previous one, so no point is silently overwritten.
+	Precision lineprotocol.Precision
+
+	// Selector picks which point is written next. If nil, every point
+	// is written in turn.
+	Selector Selector
+
+	// Counts, if not nil, has Counts[i] incremented every time pts[i]
+	// is written.
+	Counts []uint64
+
+	// ChurnRate is the fraction of the points whose series are replaced with
+	// new ones every ChurnInterval, oldest first. Only points implementing
+	// Churner are replaced. If 0, the series never change.
+	ChurnRate     float64
+	ChurnInterval time.Duration
+
+	// Churned, if not nil, is atomically incremented for every series replaced.
+	Churned *uint64
+
+	// Interval, if non-zero, stamps the points with a simulated clock instead
+	// of the time of Tick. The clock starts at Start and advances by Interval
+	// after every len(pts) points, and every point is written once per
+	// Interval, the Selector only deciding their order. Writing stops when
+	// the clock reaches End, unless End is zero. Tick still paces the batches.
+	Start    time.Time
+	End      time.Time
+	Interval time.Duration
+
+	// Disorder describes the points written out of order. The zero value
+	// writes every point in order.
+	Disorder Disorder
+
+	// Rand, if not nil, is the source of randomness of the write and of the
+	// points implementing Randomizer. It must not be used by other goroutines
+	// while Write runs. Otherwise a source seeded from the current time is used.
+	Rand *rand.Rand
+
 	Deadline time.Time
 	Tick     <-chan time.Time
 	Results  chan<- WriteResult
 }
 
+// Disorder describes the fractions of the points written late, as duplicates
+// or dated in the future. These points leave the timestamps of the points
+// written after them in their series unchanged.
+type Disorder struct {
+	// LateRate is the fraction of the points stamped LateOffset() before
+	// the time they would be written with.
+	LateRate   float64
+	LateOffset func() time.Duration
+
+	// DuplicateRate is the fraction of the points written with the same
+	// timestamp as the previous point of their series, but new values.
+	DuplicateRate float64
+
+	// FutureRate is the fraction of the points stamped FutureOffset() after
+	// the time they would be written with.
+	FutureRate   float64
+	FutureOffset func() time.Duration
+
+	// Late, Duplicates and Future, if not nil, are atomically incremented
+	// for every point of the kind written.
+	Late, Duplicates, Future *uint64
+}
+
+// stamp returns the time to write pts[i] with, given the time t of the
+// batch and the last timestamp of each point, drawing its randomness from r.
+// The last timestamp of pts[i] is only updated when it is written in order.
+func (d *Disorder) stamp(r *rand.Rand, t time.Time, last []time.Time, i int, unit time.Duration) time.Time {
+	next := nextTime(t, last[i], unit)
+
+	if d.LateRate+d.DuplicateRate+d.FutureRate > 0 {
+		x := r.Float64()
+		switch {
+		case x < d.LateRate:
+			count(d.Late)
+			return next.Add(-d.LateOffset())
+		case x < d.LateRate+d.DuplicateRate:
+			if last[i].IsZero() {
+				// The series has no point to duplicate yet.
+				break
+			}
+			count(d.Duplicates)
+			return last[i]
+		case x < d.LateRate+d.DuplicateRate+d.FutureRate:
+			count(d.Future)
+			return next.Add(d.FutureOffset())
+		}
+	}
+
+	last[i] = next
+	return next
+}
+
+// count atomically increments n if it is not nil.
+func count(n *uint64) {
+	if n != nil {
+		atomic.AddUint64(n, 1)
+	}
+}
+
+// A Randomizer is a point whose values take their randomness from a source
+// set by Write.
+type Randomizer interface {
+	SetRand(r *rand.Rand)
+}
+
+// A Churner is a point whose series can be replaced with a new one.
+type Churner interface {
+	Churn()
+}
+
+// churner replaces the series of a fraction of the points at a fixed interval.
+type churner struct {
+	pts      []lineprotocol.Point
+	rate     float64
+	interval time.Duration
+	churned  *uint64
+
+	next   time.Time
+	carry  float64
+	cursor int
+}
+
+// churn replaces the series due by time t.
+func (c *churner) churn(t time.Time) {
+	for !t.Before(c.next) {
+		c.next = c.next.Add(c.interval)
+
+		n := c.rate*float64(len(c.pts)) + c.carry
+		c.carry = n - float64(int(n))
+		for i := 0; i < int(n); i++ {
+			if ch, ok := c.pts[c.cursor].(Churner); ok {
+				ch.Churn()
+				count(c.churned)
+			}
+			c.cursor = (c.cursor + 1) % len(c.pts)
+		}
+	}
+}
+
 // Write takes in a slice of lineprotocol.Points, a write.Client, and a WriteConfig. It will attempt
 // to write data to the target until one of the following conditions is met.
 // 1. We reach that MaxPoints specified in the WriteConfig.
@@ -43,11 +184,53 @@ func Write(pts []lineprotocol.Point, c write.Client, cfg WriteConfig) (uint64, t
 		panic("Results Channel on WriteConfig cannot be nil")
 	}
 	var pointCount uint64
+	if len(pts) == 0 {
+		return pointCount, 0
+	}
 
 	start := time.Now()
 	buf := bytes.NewBuffer(nil)
 	t := time.Now()
 
+	// clock is the time the points are stamped with.
+	clock := t
+	if cfg.Interval > 0 {
+		clock = cfg.Start
+	}
+
+	// last holds the most recent timestamp given to each point.
+	unit := cfg.Precision.Duration()
+	last := make([]time.Time, len(pts))
+
+	r := cfg.Rand
+	if r == nil {
+		r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	for _, pt := range pts {
+		if rz, ok := pt.(Randomizer); ok {
+			rz.SetRand(r)
+		}
+	}
+
+	sel := cfg.Selector
+	if sel == nil {
+		sel = &roundRobin{n: len(pts)}
+	}
+	if cfg.Interval > 0 {
+		sel = newOnceEach(sel, len(pts))
+	}
+
+	var ch *churner
+	if cfg.ChurnRate > 0 && cfg.ChurnInterval > 0 {
+		ch = &churner{
+			pts:      pts,
+			rate:     cfg.ChurnRate,
+			interval: cfg.ChurnInterval,
+			churned:  cfg.Churned,
+			next:     clock.Add(cfg.ChurnInterval),
+		}
+	}
+
 	var w io.Writer = buf
 
 	doGzip := cfg.GzipLevel != 0
@@ -62,7 +245,36 @@ func Write(pts []lineprotocol.Point, c write.Client, cfg WriteConfig) (uint64, t
 		w = gzw
 	}
 
-	tPrev := t
+	// An Encoder is written its own format instead of line protocol,
+	// unless the batches are gzipped.
+	enc, _ := c.(write.Encoder)
+	if doGzip {
+		enc = nil
+	}
+	var encoded []byte
+
+	flush := func() {
+		if enc != nil {
+			sendBatch(enc.SendEncoded, encoded, cfg.Results)
+			encoded = encoded[:0]
+			return
+		}
+
+		if doGzip {
+			// Must Close, not Flush, to write full gzip content to underlying bytes buffer.
+			if err := gzw.Close(); err != nil {
+				panic(err)
+			}
+		}
+		sendBatch(c.Send, buf.Bytes(), cfg.Results)
+		buf.Reset()
+		if doGzip {
+			// The bytes buffer was reset.
+			// Reset the gzip writer to start clean.
+			gzw.Reset(buf)
+		}
+	}
+
 WRITE_BATCHES:
 	for {
 		if t.After(cfg.Deadline) {
@@ -73,25 +285,39 @@ WRITE_BATCHES:
 			break
 		}
 
-		for _, pt := range pts {
+		if cfg.Interval > 0 && !cfg.End.IsZero() && !clock.Before(cfg.End) {
+			// Send the points of the last intervals that did not fill a batch.
+			if pointCount%cfg.BatchSize != 0 {
+				flush()
+			}
+			break
+		}
+
+		for j := 0; j < len(pts); j++ {
+			i := sel.Next()
+			pt := pts[i]
+			if cfg.Counts != nil {
+				cfg.Counts[i]++
+			}
+
 			pointCount++
-			pt.SetTime(t)
-			lineprotocol.WritePoint(w, pt)
-			if pointCount%cfg.BatchSize == 0 {
-				if doGzip {
-					// Must Close, not Flush, to write full gzip content to underlying bytes buffer.
-					if err := gzw.Close(); err != nil {
-						panic(err)
-					}
-				}
-				sendBatch(c, buf, cfg.Results)
-				if doGzip {
-					// sendBatch already reset the bytes buffer.
-					// Reset the gzip writer to start clean.
-					gzw.Reset(buf)
+			pt.SetTime(cfg.Disorder.stamp(r, clock, last, i, unit))
+			if enc != nil {
+				var err error
+				if encoded, err = enc.AppendPoint(encoded, pt); err != nil {
+					// Should only happen with an invalid series key.
+					panic(err)
 				}
+			} else {
+				lineprotocol.WritePoint(w, pt)
+			}
+			if pointCount%cfg.BatchSize == 0 {
+				flush()
 
 				t = <-cfg.Tick
+				if cfg.Interval == 0 {
+					clock = t
+				}
 				if t.After(cfg.Deadline) {
 					break WRITE_BATCHES
 				}
@@ -100,24 +326,35 @@ WRITE_BATCHES:
 					break
 				}
 
+				if ch != nil {
+					ch.churn(clock)
+				}
+
 			}
 			pt.Update()
 		}
 
-		// Avoid timestamp colision when batch size > pts
-		if t.After(tPrev) {
-			tPrev = t
-			continue
+		if cfg.Interval > 0 {
+			clock = clock.Add(cfg.Interval)
 		}
-		t = t.Add(1 * time.Nanosecond)
 	}
 
 	return pointCount, time.Since(start)
 }
 
-func sendBatch(c write.Client, buf *bytes.Buffer, ch chan<- WriteResult) {
-	lat, status, body, err := c.Send(buf.Bytes())
-	buf.Reset()
+// nextTime returns t, unless t falls in the same unit as prev or an earlier one.
+// This happens when batch size > pts or when the precision is coarser than the
+// tick, and then the start of the unit after prev is returned instead.
+func nextTime(t, prev time.Time, unit time.Duration) time.Time {
+	if t.Truncate(unit).After(prev.Truncate(unit)) {
+		return t
+	}
+
+	return prev.Truncate(unit).Add(unit)
+}
+
+func sendBatch(send func([]byte) (int64, int, string, error), b []byte, ch chan<- WriteResult) {
+	lat, status, body, err := send(b)
 	select {
 	case ch <- WriteResult{LatNs: lat, StatusCode: status, Body: body, Err: err, Timestamp: time.Now().UnixNano()}:
 	default:
@@ -0,0 +1,376 @@
+package stress_test
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+	"github.com/influxdata/influx-stress/point"
+	"github.com/influxdata/influx-stress/stress"
+)
+
+type bufferClient struct {
+	buf bytes.Buffer
+}
+
+func (c *bufferClient) Create(string) error { return nil }
+
+func (c *bufferClient) Send(b []byte) (int64, int, string, error) {
+	c.buf.Write(b)
+	return 0, 204, "", nil
+}
+
+func (c *bufferClient) Close() error { return nil }
+
+// sameTick returns a channel on which every tick lands in the same second.
+func sameTick() <-chan time.Time {
+	tick := make(chan time.Time)
+	go func() {
+		for {
+			tick <- time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+		}
+	}()
+
+	return tick
+}
+
+// checkNoCollisions fails if any series was written twice with the same timestamp.
+func checkNoCollisions(t *testing.T, c *bufferClient, n int) {
+	seen := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(c.buf.String()), "\n") {
+		parts := strings.Split(line, " ")
+		key := parts[0] + " " + parts[2]
+		if seen[key] {
+			t.Fatalf("Series %v was written twice with timestamp %v", parts[0], parts[2])
+		}
+		seen[key] = true
+	}
+
+	if got, exp := len(seen), n; got != exp {
+		t.Errorf("Wrong number of lines written. got %v, exp %v", got, exp)
+	}
+}
+
+func TestWrite_NoTimestampCollisions(t *testing.T) {
+	pts, err := point.NewPoints("cpu,host=server", "n=0i", 2, point.Config{Precision: lineprotocol.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The batches are larger than the number of points.
+	c := &bufferClient{}
+	cfg := stress.WriteConfig{
+		BatchSize: 5,
+		MaxPoints: 20,
+		Precision: lineprotocol.Second,
+		Deadline:  time.Now().Add(time.Minute),
+		Tick:      sameTick(),
+		Results:   make(chan stress.WriteResult, 10),
+	}
+
+	if n, _ := stress.Write(pts, c, cfg); n != 20 {
+		t.Fatalf("Wrong number of points written. got %v, exp %v", n, 20)
+	}
+
+	checkNoCollisions(t, c, 20)
+}
+
+func TestWrite_Selector(t *testing.T) {
+	pts, err := point.NewPoints("cpu,host=server", "n=0i", 10, point.Config{Precision: lineprotocol.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sel, err := stress.NewSelector("zipf(2)", len(pts), rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &bufferClient{}
+	cfg := stress.WriteConfig{
+		BatchSize: 10,
+		MaxPoints: 100,
+		Precision: lineprotocol.Second,
+		Selector:  sel,
+		Counts:    make([]uint64, len(pts)),
+		Deadline:  time.Now().Add(time.Minute),
+		Tick:      sameTick(),
+		Results:   make(chan stress.WriteResult, 10),
+	}
+
+	if n, _ := stress.Write(pts, c, cfg); n != 100 {
+		t.Fatalf("Wrong number of points written. got %v, exp %v", n, 100)
+	}
+
+	var total uint64
+	for _, n := range cfg.Counts {
+		total += n
+	}
+	if total != 100 {
+		t.Errorf("Wrong total of per series counts. got %v, exp %v", total, 100)
+	}
+
+	if cfg.Counts[0] <= cfg.Counts[9] {
+		t.Errorf("Writes were not skewed towards the first series: %v", cfg.Counts)
+	}
+
+	checkNoCollisions(t, c, 100)
+}
+
+func TestWrite_Churn(t *testing.T) {
+	pts, err := point.NewPoints("cpu,host=server", "n=0i", 10, point.Config{Precision: lineprotocol.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every batch is a minute after the previous one.
+	tick := make(chan time.Time)
+	go func() {
+		for ts := time.Now(); ; ts = ts.Add(time.Minute) {
+			tick <- ts
+		}
+	}()
+
+	var churned uint64
+	c := &bufferClient{}
+	cfg := stress.WriteConfig{
+		BatchSize:     10,
+		MaxPoints:     50,
+		Precision:     lineprotocol.Second,
+		ChurnRate:     0.25,
+		ChurnInterval: time.Minute,
+		Churned:       &churned,
+		Deadline:      time.Now().Add(time.Hour),
+		Tick:          tick,
+		Results:       make(chan stress.WriteResult, 10),
+	}
+
+	stress.Write(pts, c, cfg)
+
+	// 2.5 series are replaced every minute, before each of the third, fourth
+	// and fifth batches.
+	if got, exp := churned, uint64(7); got != exp {
+		t.Errorf("Wrong number of series churned. got %v, exp %v", got, exp)
+	}
+
+	series := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(c.buf.String()), "\n") {
+		series[strings.Split(line, " ")[0]] = true
+	}
+
+	if got, exp := len(series), 10+7; got != exp {
+		t.Errorf("Wrong number of distinct series written. got %v, exp %v", got, exp)
+	}
+}
+
+func TestWrite_SimulatedClock(t *testing.T) {
+	pts, err := point.NewPoints("cpu,host=server", "n=0i", 3, point.Config{Precision: lineprotocol.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last batch is not full, and must still be sent.
+	start := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	c := &bufferClient{}
+	cfg := stress.WriteConfig{
+		BatchSize: 4,
+		MaxPoints: 100,
+		Precision: lineprotocol.Second,
+		Start:     start,
+		End:       start.Add(time.Minute),
+		Interval:  10 * time.Second,
+		Deadline:  time.Now().Add(time.Minute),
+		Tick:      sameTick(),
+		Results:   make(chan stress.WriteResult, 10),
+	}
+
+	if n, _ := stress.Write(pts, c, cfg); n != 18 {
+		t.Fatalf("Wrong number of points written. got %v, exp %v", n, 18)
+	}
+
+	times := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(c.buf.String()), "\n") {
+		parts := strings.Split(line, " ")
+		times[parts[0]] = append(times[parts[0]], parts[2])
+	}
+
+	exp := []string{"1257894000", "1257894010", "1257894020", "1257894030", "1257894040", "1257894050"}
+	for _, p := range pts {
+		if got := times[string(p.Series())]; strings.Join(got, ",") != strings.Join(exp, ",") {
+			t.Errorf("Wrong timestamps for %s. got %v, exp %v", p.Series(), got, exp)
+		}
+	}
+}
+
+func TestWrite_SimulatedClock_Weighted(t *testing.T) {
+	cpu, err := point.NewPoints("cpu,host=server", "n=0i", 2, point.Config{Precision: lineprotocol.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem, err := point.NewPoints("mem,host=server", "n=0i", 2, point.Config{Precision: lineprotocol.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts := append(cpu, mem...)
+
+	groups := []stress.Group{}
+	for i, weight := range []float64{3, 1} {
+		sel, err := stress.NewSelector("zipf(2)", 2, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups = append(groups, stress.Group{Offset: 2 * i, Selector: sel, Weight: weight})
+	}
+
+	// The weights and distributions only change the order of the points
+	// within each interval.
+	start := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	c := &bufferClient{}
+	cfg := stress.WriteConfig{
+		BatchSize: 1,
+		MaxPoints: 100,
+		Precision: lineprotocol.Second,
+		Selector:  stress.NewWeightedSelector(groups),
+		Start:     start,
+		End:       start.Add(40 * time.Second),
+		Interval:  10 * time.Second,
+		Deadline:  time.Now().Add(time.Minute),
+		Tick:      sameTick(),
+		Results:   make(chan stress.WriteResult, 100),
+	}
+
+	if n, _ := stress.Write(pts, c, cfg); n != 16 {
+		t.Fatalf("Wrong number of points written. got %v, exp %v", n, 16)
+	}
+
+	lines := strings.Split(strings.TrimSpace(c.buf.String()), "\n")
+	times := map[string][]string{}
+	for _, line := range lines {
+		parts := strings.Split(line, " ")
+		times[parts[0]] = append(times[parts[0]], parts[2])
+	}
+
+	exp := []string{"1257894000", "1257894010", "1257894020", "1257894030"}
+	for _, p := range pts {
+		if got := times[string(p.Series())]; strings.Join(got, ",") != strings.Join(exp, ",") {
+			t.Errorf("Wrong timestamps for %s. got %v, exp %v", p.Series(), got, exp)
+		}
+	}
+
+	if !strings.HasPrefix(lines[0], "cpu") {
+		t.Errorf("Expected the heavier measurement to be written first, got %v", lines[0])
+	}
+}
+
+func TestWrite_Disorder(t *testing.T) {
+	hour := func() time.Duration { return time.Hour }
+	start := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		disorder stress.Disorder
+		// exp is the timestamps written for the series, in seconds after start.
+		exp []int64
+		// n is the number of points counted as out of order.
+		n uint64
+	}{
+		{name: "in order", exp: []int64{0, 10, 20, 30}},
+		{name: "late", disorder: stress.Disorder{LateRate: 1, LateOffset: hour}, exp: []int64{-3600, -3590, -3580, -3570}, n: 4},
+		// The first point of the series cannot be a duplicate.
+		{name: "duplicate", disorder: stress.Disorder{DuplicateRate: 1}, exp: []int64{0, 0, 0, 0}, n: 3},
+		{name: "future", disorder: stress.Disorder{FutureRate: 1, FutureOffset: hour}, exp: []int64{3600, 3610, 3620, 3630}, n: 4},
+	}
+
+	for _, test := range tests {
+		pts, err := point.NewPoints("cpu,host=server", "n=0i", 1, point.Config{Precision: lineprotocol.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var n uint64
+		test.disorder.Late, test.disorder.Duplicates, test.disorder.Future = &n, &n, &n
+
+		c := &bufferClient{}
+		cfg := stress.WriteConfig{
+			BatchSize: 1,
+			MaxPoints: 4,
+			Precision: lineprotocol.Second,
+			Start:     start,
+			Interval:  10 * time.Second,
+			Disorder:  test.disorder,
+			Deadline:  time.Now().Add(time.Minute),
+			Tick:      sameTick(),
+			Results:   make(chan stress.WriteResult, 10),
+		}
+		stress.Write(pts, c, cfg)
+
+		got := []int64{}
+		for _, line := range strings.Split(strings.TrimSpace(c.buf.String()), "\n") {
+			ts, err := strconv.ParseInt(strings.Split(line, " ")[2], 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, ts-start.Unix())
+		}
+
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("%s: wrong timestamps written. got %v, exp %v", test.name, got, test.exp)
+		}
+
+		if n != test.n {
+			t.Errorf("%s: wrong number of points counted. got %v, exp %v", test.name, n, test.n)
+		}
+	}
+}
+
+func TestWrite_Seed(t *testing.T) {
+	start := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	run := func(seed int64) string {
+		pts, err := point.NewPoints("cpu,host=#10", `usage=randwalk(0,100,1),n=uniform(0,10)i?0.5,msg="x"`, 10, point.Config{
+			Precision:    lineprotocol.Second,
+			StringLength: 4,
+			StringPool:   3,
+			Rand:         rand.New(rand.NewSource(seed)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := rand.New(rand.NewSource(seed + 1))
+		sel, err := stress.NewSelector("zipf(1.5)", len(pts), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := &bufferClient{}
+		cfg := stress.WriteConfig{
+			BatchSize: 7,
+			MaxPoints: 100,
+			Precision: lineprotocol.Second,
+			Selector:  sel,
+			Start:     start,
+			Interval:  10 * time.Second,
+			Disorder:  stress.Disorder{LateRate: 0.1, LateOffset: func() time.Duration { return time.Minute }, DuplicateRate: 0.1},
+			Rand:      r,
+			Deadline:  time.Now().Add(time.Minute),
+			Tick:      sameTick(),
+			Results:   make(chan stress.WriteResult, 100),
+		}
+		stress.Write(pts, c, cfg)
+
+		return c.buf.String()
+	}
+
+	if a, b := run(1), run(1); a != b {
+		t.Errorf("Runs with the same seed wrote different points:\n%v\n%v", a, b)
+	}
+
+	if a, b := run(1), run(2); a == b {
+		t.Errorf("Runs with different seeds wrote the same points:\n%v", a)
+	}
+}
@@ -1,15 +1,20 @@
 package write
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/influxdata/influx-stress/lineprotocol"
 	"github.com/valyala/fasthttp"
 )
 
@@ -30,6 +35,28 @@ type ClientConfig struct {
 	TLSSkipVerify   bool
 
 	Gzip bool
+
+	// API is the version of the write API, "v1" if empty. The v2 API
+	// writes to Bucket in Org, authenticated with Token.
+	API    string
+	Org    string
+	Bucket string
+	Token  string
+
+	// AcceptPartial and NoSync are the options of the v3 API. With
+	// AcceptPartial the valid lines of a batch are written even if some are
+	// rejected, and with NoSync writes are acknowledged before they are
+	// persisted to the write-ahead log.
+	AcceptPartial bool
+	NoSync        bool
+
+	// UDPPayload is the maximum size of the packets of the UDP client,
+	// DefaultUDPPayload if 0.
+	UDPPayload int
+
+	// GraphiteTemplate is the template of the metric paths of the Graphite
+	// client, DefaultGraphiteTemplate if empty.
+	GraphiteTemplate string
 }
 
 type Client interface {
@@ -39,17 +66,66 @@ type Client interface {
 	Close() error
 }
 
+// An Encoder is a Client sending another format than line protocol. The
+// points are appended to a batch in that format with AppendPoint and the
+// batch sent with SendEncoded, instead of being written as line protocol
+// and parsed back by Send.
+type Encoder interface {
+	Client
+
+	AppendPoint(buf []byte, p lineprotocol.Point) ([]byte, error)
+	SendEncoded([]byte) (latNs int64, statusCode int, body string, err error)
+}
+
+// FormatPrecision returns the precision of the timestamps written in format,
+// seconds for graphite, milliseconds for opentsdb and prometheus and
+// nanoseconds otherwise. Points must be stamped at least this far apart, or
+// the ones of a series falling in the same unit overwrite each other.
+func FormatPrecision(format string) lineprotocol.Precision {
+	switch format {
+	case "graphite":
+		return lineprotocol.Second
+	case "opentsdb", "prometheus":
+		return lineprotocol.Millisecond
+	}
+
+	return lineprotocol.Nanosecond
+}
+
 type client struct {
 	url []byte
 
+	// authorization, if not empty, is sent as the Authorization header.
+	authorization []byte
+
+	// contentType is the Content-Type of the writes, text/plain if empty.
+	contentType []byte
+
+	// header holds other headers of the writes.
+	header map[string]string
+
 	cfg ClientConfig
 
 	httpClient *fasthttp.Client
+
+	// api is used for the requests other than writes.
+	api *http.Client
 }
 
 func NewClient(cfg ClientConfig) Client {
+	return newClient(cfg, writeURLFromConfig(cfg))
+}
+
+// newClient returns a client sending its writes to writeURL.
+func newClient(cfg ClientConfig, writeURL string) *client {
 	var httpClient *fasthttp.Client
-	if cfg.TLSSkipVerify {
+	if sock := unixSocket(cfg.BaseURL); sock != "" {
+		httpClient = &fasthttp.Client{
+			Dial: func(string) (net.Conn, error) {
+				return net.Dial("unix", sock)
+			},
+		}
+	} else if cfg.TLSSkipVerify {
 		httpClient = &fasthttp.Client{
 			TLSConfig: &tls.Config{
 				InsecureSkipVerify: true,
@@ -57,12 +133,59 @@ func NewClient(cfg ClientConfig) Client {
 		}
 	}
 	return &client{
-		url:        []byte(writeURLFromConfig(cfg)),
+		url:        []byte(writeURL),
 		cfg:        cfg,
 		httpClient: httpClient,
+		api:        apiHTTPClient(cfg),
+	}
+}
+
+// apiHTTPClient returns the client of the requests other than writes.
+func apiHTTPClient(cfg ClientConfig) *http.Client {
+	if sock := unixSocket(cfg.BaseURL); sock != "" {
+		return &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", sock)
+				},
+			},
+		}
+	}
+
+	if !cfg.TLSSkipVerify {
+		return http.DefaultClient
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		},
 	}
 }
 
+// unixSocket returns the path of the socket of a unix:// base URL, such as
+// unix:///var/run/influxdb.sock, or "" for other addresses.
+func unixSocket(base string) string {
+	if !strings.HasPrefix(base, "unix://") {
+		return ""
+	}
+
+	return strings.TrimPrefix(base, "unix://")
+}
+
+// httpBaseURL returns the base URL of the HTTP requests to base, with the
+// requests over a unix socket sent to localhost.
+func httpBaseURL(base string) string {
+	if unixSocket(base) != "" {
+		return "http://localhost"
+	}
+
+	return base
+}
+
 func (c *client) Create(command string) error {
 	if command == "" {
 		command = "CREATE DATABASE " + c.cfg.Database
@@ -70,14 +193,14 @@ func (c *client) Create(command string) error {
 
 	vals := url.Values{}
 	vals.Set("q", command)
-	u, err := url.Parse(c.cfg.BaseURL)
+	u, err := url.Parse(httpBaseURL(c.cfg.BaseURL))
 	if err != nil {
 		return err
 	}
 	if c.cfg.User != "" && c.cfg.Pass != "" {
 		u.User = url.UserPassword(c.cfg.User, c.cfg.Pass)
 	}
-	resp, err := http.PostForm(u.String()+"/query", vals)
+	resp, err := c.api.PostForm(u.String()+"/query", vals)
 	if err != nil {
 		return err
 	}
@@ -96,12 +219,22 @@ func (c *client) Create(command string) error {
 
 func (c *client) Send(b []byte) (latNs int64, statusCode int, body string, err error) {
 	req := fasthttp.AcquireRequest()
-	req.Header.SetContentTypeBytes([]byte("text/plain"))
+	if len(c.contentType) > 0 {
+		req.Header.SetContentTypeBytes(c.contentType)
+	} else {
+		req.Header.SetContentTypeBytes([]byte("text/plain"))
+	}
 	req.Header.SetMethodBytes([]byte("POST"))
 	req.Header.SetRequestURIBytes(c.url)
 	if c.cfg.Gzip {
 		req.Header.SetBytesKV([]byte("Content-Encoding"), []byte("gzip"))
 	}
+	if len(c.authorization) > 0 {
+		req.Header.SetBytesKV([]byte("Authorization"), c.authorization)
+	}
+	for k, v := range c.header {
+		req.Header.Set(k, v)
+	}
 	req.Header.SetContentLength(len(b))
 	req.SetBody(b)
 
@@ -200,6 +333,13 @@ func (c *fileClient) Close() error {
 }
 
 func writeURLFromConfig(cfg ClientConfig) string {
+	switch cfg.API {
+	case "v2":
+		return v2WriteURL(cfg)
+	case "v3":
+		return v3WriteURL(cfg)
+	}
+
 	params := url.Values{}
 	params.Set("db", cfg.Database)
 	if cfg.User != "" {
@@ -218,5 +358,43 @@ func writeURLFromConfig(cfg ClientConfig) string {
 		params.Set("consistency", cfg.Consistency)
 	}
 
-	return cfg.BaseURL + "/write?" + params.Encode()
+	return httpBaseURL(cfg.BaseURL) + "/write?" + params.Encode()
+}
+
+// newSocketClient returns the UDP or TCP client of the scheme of cfg.BaseURL.
+func newSocketClient(cfg ClientConfig) (Client, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		return NewTCPClient(cfg)
+	case "udp":
+		return NewUDPClient(cfg)
+	}
+
+	return nil, errors.New("expected a tcp:// or udp:// address, got " + cfg.BaseURL)
+}
+
+// withPacketCounter returns c, also implementing PacketCounter if its
+// transport does.
+func withPacketCounter(c, transport Client) Client {
+	pc, ok := transport.(PacketCounter)
+	if !ok {
+		return c
+	}
+
+	if enc, ok := c.(Encoder); ok {
+		return struct {
+			Encoder
+			PacketCounter
+		}{enc, pc}
+	}
+
+	return struct {
+		Client
+		PacketCounter
+	}{c, pc}
 }
@@ -1,6 +1,61 @@
 package write_test
 
-import "testing"
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influx-stress/write"
+)
 
 func TestNewClient(t *testing.T) {}
 func TestSend(t *testing.T)      {}
+
+func TestClient_UnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "influxdb.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.URL.Path+" "+r.Form.Get("q")+string(b))
+		if r.URL.Path == "/write" {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	ts.Listener = l
+	ts.Start()
+	defer ts.Close()
+
+	c := write.NewClient(write.ClientConfig{BaseURL: "unix://" + sock, Database: "stress"})
+	if err := c.Create(""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, status, _, err := c.Send([]byte("cpu n=1i 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusNoContent)
+	}
+
+	exp := []string{"/query CREATE DATABASE stress", "/write cpu n=1i 1\n"}
+	if len(requests) != 2 || requests[0] != exp[0] || requests[1] != exp[1] {
+		t.Errorf("Wrong requests received. got %q, exp %q", requests, exp)
+	}
+}
@@ -0,0 +1,120 @@
+package write
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// DumpReader reads line protocol back from a file written by the client
+// returned by NewFileClient, or from any line protocol file. Gzipped input,
+// and the gzipped batches of a dump written with gzip, are detected and
+// decompressed.
+type DumpReader struct {
+	// URL is the write URL in the header of a dump, or empty if the input
+	// has no header.
+	URL string
+
+	r *bufio.Reader
+
+	// batch, if not nil, reads the lines of a gzipped batch of r.
+	batch *bufio.Reader
+
+	// first is set when a batch header was read since the last line.
+	first bool
+}
+
+// NewDumpReader returns a DumpReader reading from r.
+func NewDumpReader(r io.Reader) (*DumpReader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gzr)
+	}
+
+	d := &DumpReader{r: br}
+
+	// The header of a dump is its first line, a comment with the write URL.
+	if b, err := br.Peek(2); err == nil && string(b) == "# " {
+		line, err := d.readLine()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if bytes.Contains(line, []byte("write?")) || bytes.Contains(line, []byte("write_lp?")) {
+			d.URL = string(line[2:])
+		}
+	}
+
+	return d, nil
+}
+
+// Next returns the next line of line protocol, without its newline, and
+// whether it is the first line of a batch of the dump. Comments and empty
+// lines are skipped. At the end of the input it returns io.EOF.
+func (d *DumpReader) Next() (line []byte, first bool, err error) {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch {
+		case bytes.HasPrefix(line, []byte("# Batch ")):
+			d.first = true
+			if err := d.readGzipBatch(); err != nil {
+				return nil, false, err
+			}
+		case len(line) == 0 || line[0] == '#':
+		default:
+			first, d.first = d.first, false
+			return line, first, nil
+		}
+	}
+}
+
+// readGzipBatch starts reading the batch after a batch header from its
+// decompressed lines if it is gzipped.
+func (d *DumpReader) readGzipBatch() error {
+	if magic, err := d.r.Peek(2); err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return nil
+	}
+
+	gzr, err := gzip.NewReader(d.r)
+	if err != nil {
+		return err
+	}
+	// The batch ends with its gzip member, the rest of the dump follows.
+	gzr.Multistream(false)
+	d.batch = bufio.NewReader(gzr)
+
+	return nil
+}
+
+// readLine returns the next line, without its trailing newline or carriage
+// return. The last line of the input or of a gzipped batch may end without
+// a newline.
+func (d *DumpReader) readLine() ([]byte, error) {
+	r := d.r
+	if d.batch != nil {
+		r = d.batch
+	}
+
+	line, err := r.ReadBytes('\n')
+	line = bytes.TrimRight(line, "\r\n")
+	if err == io.EOF && d.batch != nil {
+		d.batch = nil
+		if len(line) > 0 {
+			return line, nil
+		}
+		return d.readLine()
+	}
+	if err == io.EOF && len(line) > 0 {
+		return line, nil
+	}
+
+	return line, err
+}
@@ -0,0 +1,158 @@
+package write_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influx-stress/write"
+)
+
+// readAll returns the lines read by d, each prefixed with "| " if it is the
+// first line of a batch.
+func readAll(t *testing.T, d *write.DumpReader) []string {
+	lines := []string{}
+	for {
+		line, first, err := d.Next()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first {
+			lines = append(lines, "| "+string(line))
+		} else {
+			lines = append(lines, string(line))
+		}
+	}
+}
+
+func TestDumpReader_FileClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dump.lp")
+	c, err := write.NewFileClient(path, write.ClientConfig{Database: "db", Precision: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Create("")
+	c.Send([]byte("cpu n=1i 1\ncpu n=2i 2\n"))
+	c.Send([]byte("cpu n=3i 3\n"))
+	c.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	d, err := write.NewDumpReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := d.URL, "/write?db=db&precision=s"; got != exp {
+		t.Errorf("Wrong URL read. got %v, exp %v", got, exp)
+	}
+
+	if got, exp := readAll(t, d), []string{"| cpu n=1i 1", "cpu n=2i 2", "| cpu n=3i 3"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong lines read. got %v, exp %v", got, exp)
+	}
+}
+
+func TestDumpReader_GzipBatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gzipped := func(b string) []byte {
+		buf := bytes.NewBuffer(nil)
+		gzw := gzip.NewWriter(buf)
+		gzw.Write([]byte(b))
+		gzw.Close()
+		return buf.Bytes()
+	}
+
+	path := filepath.Join(dir, "dump.lp")
+	c, err := write.NewFileClient(path, write.ClientConfig{Database: "db", Precision: "s", Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Create("")
+	c.Send(gzipped("cpu n=1i 1\ncpu n=2i 2\n"))
+	c.Send(gzipped("cpu n=3i 3\ncpu n=4i 4"))
+	c.Send(gzipped("cpu n=5i 5\n"))
+	c.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	d, err := write.NewDumpReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"| cpu n=1i 1", "cpu n=2i 2", "| cpu n=3i 3", "cpu n=4i 4", "| cpu n=5i 5"}
+	if got := readAll(t, d); !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong lines read. got %v, exp %v", got, exp)
+	}
+}
+
+func TestDumpReader_V3(t *testing.T) {
+	d, err := write.NewDumpReader(strings.NewReader("# /api/v3/write_lp?db=db&precision=second\ncpu n=1i 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := d.URL, "/api/v3/write_lp?db=db&precision=second"; got != exp {
+		t.Errorf("Wrong URL read. got %v, exp %v", got, exp)
+	}
+}
+
+func TestDumpReader_Gzip(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	gzw := gzip.NewWriter(buf)
+	gzw.Write([]byte("# a comment\ncpu n=1i 1\r\n\ncpu n=2i 2"))
+	gzw.Close()
+
+	d, err := write.NewDumpReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.URL != "" {
+		t.Errorf("Unexpected URL read: %v", d.URL)
+	}
+
+	if got, exp := readAll(t, d), []string{"cpu n=1i 1", "cpu n=2i 2"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong lines read. got %v, exp %v", got, exp)
+	}
+}
+
+func TestDumpReader_Empty(t *testing.T) {
+	d, err := write.NewDumpReader(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readAll(t, d); len(got) != 0 {
+		t.Errorf("Unexpected lines read: %v", got)
+	}
+}
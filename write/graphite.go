@@ -0,0 +1,214 @@
+package write
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+)
+
+// DefaultGraphiteTemplate is the default template of the metric paths of the
+// Graphite client.
+const DefaultGraphiteTemplate = "measurement.tags.field"
+
+// graphiteClient writes the points in the Graphite plaintext protocol, a
+// `path value timestamp` line per numeric field, and sends them to a socket
+// listener. Batches of line protocol are converted.
+type graphiteClient struct {
+	Client
+
+	precision lineprotocol.Precision
+	template  graphiteTemplate
+}
+
+// NewGraphiteClient returns a Client sending the points written to it to
+// the tcp:// or udp:// address of cfg.BaseURL, with metric paths following
+// cfg.GraphiteTemplate. String fields are not sent.
+func NewGraphiteClient(cfg ClientConfig) (Client, error) {
+	p, err := lineprotocol.ParsePrecision(cfg.Precision)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parseGraphiteTemplate(cfg.GraphiteTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newSocketClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return withPacketCounter(&graphiteClient{Client: c, precision: p, template: t}, c), nil
+}
+
+// Send converts the batch b of line protocol and sends it.
+func (c *graphiteClient) Send(b []byte) (latNs int64, statusCode int, body string, err error) {
+	pts, err := lineprotocol.ParsePoints(b, c.precision)
+	if err != nil {
+		return 0, -1, "", err
+	}
+
+	buf := make([]byte, 0, 2*len(b))
+	for _, pt := range pts {
+		buf = c.appendLines(buf, pt.Measurement, pt.Tags, pt.Fields(), pt.Time().Time())
+	}
+
+	return c.SendEncoded(buf)
+}
+
+// AppendPoint appends the lines of the fields of p to buf.
+func (c *graphiteClient) AppendPoint(buf []byte, p lineprotocol.Point) ([]byte, error) {
+	var (
+		tagsBuf   [16]lineprotocol.Tag
+		fieldsBuf [16]lineprotocol.Field
+	)
+	measurement, tags, err := lineprotocol.SplitSeries(p.Series(), tagsBuf[:0])
+	if err != nil {
+		return buf, err
+	}
+
+	return c.appendLines(buf, measurement, tags, lineprotocol.WrittenFields(fieldsBuf[:0], p), p.Time().Time()), nil
+}
+
+// SendEncoded sends the lines appended to b by AppendPoint.
+func (c *graphiteClient) SendEncoded(b []byte) (latNs int64, statusCode int, body string, err error) {
+	if len(b) == 0 {
+		return 0, http.StatusNoContent, "", nil
+	}
+
+	return c.Client.Send(b)
+}
+
+// appendLines appends a line per numeric field of a point to buf.
+func (c *graphiteClient) appendLines(buf, measurement []byte, tags []lineprotocol.Tag, fields []lineprotocol.Field, t time.Time) []byte {
+	var (
+		valueBuf [32]byte
+		key      []byte
+		value    []byte
+		ok       bool
+	)
+	for _, f := range fields {
+		key, value, ok = appendFieldValue(valueBuf[:0], f)
+		if !ok {
+			continue
+		}
+
+		buf = c.template.appendPath(buf, measurement, tags, key)
+		buf = append(buf, ' ')
+		buf = append(buf, value...)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, t.Unix(), 10)
+		buf = append(buf, '\n')
+	}
+
+	return buf
+}
+
+// graphiteTemplate is the list of the dot separated parts of a template.
+// "measurement" and "field" are replaced by the measurement and field key,
+// "tags" by the values of the tags not named in the template, and any
+// other part by the value of the tag of that name, if the point has it.
+type graphiteTemplate []string
+
+func parseGraphiteTemplate(s string) (graphiteTemplate, error) {
+	if s == "" {
+		s = DefaultGraphiteTemplate
+	}
+
+	t := graphiteTemplate(strings.Split(s, "."))
+	for _, part := range t {
+		if part == "" {
+			return nil, fmt.Errorf("invalid Graphite template %q: empty part", s)
+		}
+	}
+
+	return t, nil
+}
+
+// appendPath appends the metric path of the field key of a point to buf.
+func (t graphiteTemplate) appendPath(buf, measurement []byte, tags []lineprotocol.Tag, key []byte) []byte {
+	start := len(buf)
+	appendPart := func(b []byte) {
+		if len(b) == 0 {
+			return
+		}
+		if len(buf) > start {
+			buf = append(buf, '.')
+		}
+		buf = appendGraphiteSanitized(buf, b)
+	}
+
+	for _, part := range t {
+		switch part {
+		case "measurement":
+			appendPart(measurement)
+		case "field":
+			appendPart(key)
+		case "tags":
+			for _, tag := range tags {
+				if !t.has(string(tag.Key)) {
+					appendPart(tag.Value)
+				}
+			}
+		default:
+			for _, tag := range tags {
+				if string(tag.Key) == part {
+					appendPart(tag.Value)
+				}
+			}
+		}
+	}
+
+	return buf
+}
+
+// has returns whether part is in the template.
+func (t graphiteTemplate) has(part string) bool {
+	for _, p := range t {
+		if p == part {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendGraphiteSanitized appends b to buf, with the dots and spaces that
+// would split the path or the line replaced by underscores.
+func appendGraphiteSanitized(buf, b []byte) []byte {
+	for _, c := range b {
+		switch c {
+		case '.', ' ', '\t', '\n':
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+
+	return buf
+}
+
+// appendFieldValue appends the value of a numeric or boolean field to buf,
+// with booleans as 1 or 0, and returns the field key. ok is false for
+// string fields.
+func appendFieldValue(buf []byte, f lineprotocol.Field) (key, value []byte, ok bool) {
+	switch f := f.(type) {
+	case *lineprotocol.Int:
+		return f.Key, strconv.AppendInt(buf, f.Value, 10), true
+	case *lineprotocol.Uint:
+		return f.Key, strconv.AppendUint(buf, f.Value, 10), true
+	case *lineprotocol.Float:
+		return f.Key, strconv.AppendFloat(buf, f.Value, 'f', -1, 64), true
+	case *lineprotocol.Bool:
+		if f.Value {
+			return f.Key, append(buf, '1'), true
+		}
+		return f.Key, append(buf, '0'), true
+	}
+
+	return nil, buf, false
+}
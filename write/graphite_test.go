@@ -0,0 +1,173 @@
+package write_test
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-stress/point"
+	"github.com/influxdata/influx-stress/stress"
+	"github.com/influxdata/influx-stress/write"
+)
+
+func TestGraphiteClient(t *testing.T) {
+	batch := "cpu,host=a.b,region=us\\ west n=1i,f=1.5,b=false,s=\"x\" 1\ncpu,host=c n=2u 2\n"
+
+	for _, tt := range []struct {
+		template string
+		exp      string
+	}{
+		{
+			template: "",
+			exp:      "cpu.a_b.us_west.n 1 1\ncpu.a_b.us_west.f 1.5 1\ncpu.a_b.us_west.b 0 1\ncpu.c.n 2 2\n",
+		},
+		{
+			template: "region.measurement.tags.field",
+			exp:      "us_west.cpu.a_b.n 1 1\nus_west.cpu.a_b.f 1.5 1\nus_west.cpu.a_b.b 0 1\ncpu.c.n 2 2\n",
+		},
+	} {
+		l, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		c, err := write.NewGraphiteClient(write.ClientConfig{
+			BaseURL:          "udp://" + l.LocalAddr().String(),
+			Precision:        "s",
+			GraphiteTemplate: tt.template,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		if _, _, _, err := c.Send([]byte(batch)); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, 1024)
+		l.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := l.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := string(buf[:n]); got != tt.exp {
+			t.Errorf("Wrong lines sent with template %q. got %q, exp %q", tt.template, got, tt.exp)
+		}
+
+		if _, ok := c.(write.PacketCounter); !ok {
+			t.Error("Expected the packets sent over UDP to be counted")
+		}
+	}
+}
+
+func TestGraphiteClient_Write(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p := write.FormatPrecision("graphite")
+	c, err := write.NewGraphiteClient(write.ClientConfig{BaseURL: "tcp://" + l.Addr().String(), Precision: p.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.(write.Encoder); !ok {
+		t.Fatal("Expected the points to be encoded without line protocol")
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pts, err := point.NewPoints("cpu,host=server", "n=0i", 2, point.Config{Precision: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The batches have more points than series, and are written at once.
+	tick := make(chan time.Time, 2)
+	tick <- time.Now()
+	tick <- time.Now()
+	cfg := stress.WriteConfig{
+		BatchSize: 10,
+		MaxPoints: 20,
+		Precision: p,
+		Deadline:  time.Now().Add(time.Minute),
+		Tick:      tick,
+		Results:   make(chan stress.WriteResult, 10),
+	}
+	if n, _ := stress.Write(pts, c, cfg); n != 20 {
+		t.Fatalf("Wrong number of points written. got %v, exp %v", n, 20)
+	}
+	c.Close()
+
+	seen := map[string]bool{}
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		parts := strings.Fields(s.Text())
+		sample := parts[0] + " " + parts[2]
+		if seen[sample] {
+			t.Fatalf("Path %s was written twice with timestamp %s", parts[0], parts[2])
+		}
+		seen[sample] = true
+	}
+	if got, exp := len(seen), 20; got != exp {
+		t.Errorf("Wrong number of lines written. got %v, exp %v", got, exp)
+	}
+}
+
+func TestNewGraphiteClient_invalid(t *testing.T) {
+	for _, cfg := range []write.ClientConfig{
+		{BaseURL: "http://localhost:2003"},
+		{BaseURL: "udp://localhost:2003", GraphiteTemplate: "measurement..field"},
+	} {
+		if _, err := write.NewGraphiteClient(cfg); err == nil {
+			t.Errorf("Expected an error creating a Graphite client with %+v", cfg)
+		}
+	}
+}
+
+func BenchmarkGraphiteClient_AppendPoint(b *testing.B) {
+	c, err := write.NewGraphiteClient(write.ClientConfig{BaseURL: "udp://127.0.0.1:2003", Precision: "s"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+
+	benchmarkAppendPoint(b, c)
+}
+
+// benchmarkAppendPoint measures the encoding of generated points by c,
+// which must be a write.Encoder.
+func benchmarkAppendPoint(b *testing.B, c write.Client) {
+	enc, ok := c.(write.Encoder)
+	if !ok {
+		b.Fatal("Expected the points to be encoded without line protocol")
+	}
+
+	pts, err := point.NewPoints("cpu,host=server,region=us-west", "usage_user=0,usage_system=0i,ok=true", 100, point.Config{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, pt := range pts {
+		pt.SetTime(time.Now())
+	}
+
+	var buf []byte
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if buf, err = enc.AppendPoint(buf[:0], pts[i%len(pts)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
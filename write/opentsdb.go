@@ -0,0 +1,200 @@
+package write
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+)
+
+// openTSDBClient writes the points as OpenTSDB data points, a metric named
+// measurement.field per numeric field with the tags of the point, and sends
+// them as telnet put lines over TCP or as JSON to the /api/put endpoint over
+// HTTP. Batches of line protocol are converted.
+type openTSDBClient struct {
+	Client
+
+	precision lineprotocol.Precision
+
+	// json is whether the points are sent to the HTTP API.
+	json bool
+}
+
+// NewOpenTSDBClient returns a Client sending the points written to it to the
+// OpenTSDB at cfg.BaseURL, with the put command for a tcp:// address and the
+// HTTP API otherwise. Timestamps are in milliseconds and string fields are
+// not sent.
+func NewOpenTSDBClient(cfg ClientConfig) (Client, error) {
+	p, err := lineprotocol.ParsePrecision(cfg.Precision)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &openTSDBClient{precision: p}
+	switch u.Scheme {
+	case "tcp":
+		if c.Client, err = NewTCPClient(cfg); err != nil {
+			return nil, err
+		}
+	case "http", "https", "unix":
+		hc := newClient(cfg, httpBaseURL(cfg.BaseURL)+"/api/put")
+		hc.contentType = []byte("application/json")
+		c.Client, c.json = hc, true
+	default:
+		return nil, errors.New("expected a tcp:// or http(s):// address, got " + cfg.BaseURL)
+	}
+
+	return c, nil
+}
+
+// Create does nothing, OpenTSDB creates the metrics as they are written
+// when tsd.core.auto_create_metrics is set.
+func (c *openTSDBClient) Create(command string) error {
+	return nil
+}
+
+// Send converts the batch b of line protocol and sends it. Over HTTP the
+// status code is the one of the API, 204 if every data point was stored.
+func (c *openTSDBClient) Send(b []byte) (latNs int64, statusCode int, body string, err error) {
+	pts, err := lineprotocol.ParsePoints(b, c.precision)
+	if err != nil {
+		return 0, -1, "", err
+	}
+
+	buf := make([]byte, 0, 2*len(b))
+	for _, pt := range pts {
+		buf = c.appendDataPoints(buf, pt.Measurement, pt.Tags, pt.Fields(), pt.Time().Time())
+	}
+
+	return c.SendEncoded(buf)
+}
+
+// AppendPoint appends the data points of the fields of p to buf.
+func (c *openTSDBClient) AppendPoint(buf []byte, p lineprotocol.Point) ([]byte, error) {
+	var (
+		tagsBuf   [16]lineprotocol.Tag
+		fieldsBuf [16]lineprotocol.Field
+	)
+	measurement, tags, err := lineprotocol.SplitSeries(p.Series(), tagsBuf[:0])
+	if err != nil {
+		return buf, err
+	}
+
+	return c.appendDataPoints(buf, measurement, tags, lineprotocol.WrittenFields(fieldsBuf[:0], p), p.Time().Time()), nil
+}
+
+// SendEncoded sends the data points appended to b by AppendPoint.
+func (c *openTSDBClient) SendEncoded(b []byte) (latNs int64, statusCode int, body string, err error) {
+	if len(b) == 0 {
+		return 0, http.StatusNoContent, "", nil
+	}
+
+	return c.Client.Send(b)
+}
+
+// appendDataPoints appends a data point per numeric field of a point to buf,
+// as a put command, or over HTTP to the JSON array buf holds.
+func (c *openTSDBClient) appendDataPoints(buf, measurement []byte, tags []lineprotocol.Tag, fields []lineprotocol.Field, t time.Time) []byte {
+	var (
+		valueBuf [32]byte
+		key      []byte
+		value    []byte
+		ok       bool
+	)
+	ms := t.UnixNano() / 1e6
+	for _, f := range fields {
+		key, value, ok = appendFieldValue(valueBuf[:0], f)
+		if !ok {
+			continue
+		}
+
+		if c.json {
+			buf = appendOpenTSDBJSON(buf, measurement, tags, key, value, ms)
+		} else {
+			buf = appendOpenTSDBPut(buf, measurement, tags, key, value, ms)
+		}
+	}
+
+	return buf
+}
+
+// appendOpenTSDBPut appends the put command of a data point to buf.
+func appendOpenTSDBPut(buf, measurement []byte, tags []lineprotocol.Tag, key, value []byte, ms int64) []byte {
+	buf = append(buf, "put "...)
+	buf = appendOpenTSDBMetric(buf, measurement, key)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, ms, 10)
+	buf = append(buf, ' ')
+	buf = append(buf, value...)
+	for _, tag := range tags {
+		buf = append(buf, ' ')
+		buf = appendOpenTSDBSanitized(buf, tag.Key)
+		buf = append(buf, '=')
+		buf = appendOpenTSDBSanitized(buf, tag.Value)
+	}
+
+	return append(buf, '\n')
+}
+
+// appendOpenTSDBJSON appends the object of a data point to the JSON array
+// of the /api/put endpoint in buf, starting one if buf is empty. The
+// sanitized metric and tags need no escaping.
+func appendOpenTSDBJSON(buf, measurement []byte, tags []lineprotocol.Tag, key, value []byte, ms int64) []byte {
+	if len(buf) == 0 {
+		buf = append(buf, '[')
+	} else {
+		buf[len(buf)-1] = ','
+	}
+
+	buf = append(buf, `{"metric":"`...)
+	buf = appendOpenTSDBMetric(buf, measurement, key)
+	buf = append(buf, `","timestamp":`...)
+	buf = strconv.AppendInt(buf, ms, 10)
+	buf = append(buf, `,"value":`...)
+	buf = append(buf, value...)
+	buf = append(buf, `,"tags":{`...)
+	for i, tag := range tags {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '"')
+		buf = appendOpenTSDBSanitized(buf, tag.Key)
+		buf = append(buf, `":"`...)
+		buf = appendOpenTSDBSanitized(buf, tag.Value)
+		buf = append(buf, '"')
+	}
+
+	return append(buf, "}}]"...)
+}
+
+// appendOpenTSDBMetric appends the metric name of the field key of a
+// measurement to buf.
+func appendOpenTSDBMetric(buf, measurement, key []byte) []byte {
+	buf = appendOpenTSDBSanitized(buf, measurement)
+	buf = append(buf, '.')
+	return appendOpenTSDBSanitized(buf, key)
+}
+
+// appendOpenTSDBSanitized appends b to buf, with the ASCII characters that
+// OpenTSDB does not allow in metric names and tags replaced by underscores.
+func appendOpenTSDBSanitized(buf, b []byte) []byte {
+	for _, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '/', c >= 0x80:
+		default:
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+
+	return buf
+}
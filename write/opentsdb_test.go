@@ -0,0 +1,223 @@
+package write_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+	"github.com/influxdata/influx-stress/point"
+	"github.com/influxdata/influx-stress/stress"
+	"github.com/influxdata/influx-stress/write"
+)
+
+const openTSDBBatch = "cpu,host=a,region=us\\ west n=1i,f=1.5,b=true,s=\"x\" 1\n"
+
+func TestOpenTSDBClient_HTTP(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/put" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Wrong content type. got %v, exp %v", ct, "application/json")
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		got = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c, err := write.NewOpenTSDBClient(write.ClientConfig{BaseURL: ts.URL, Precision: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Create(""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, status, _, err := c.Send([]byte(openTSDBBatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusNoContent)
+	}
+
+	tags := `"tags":{"host":"a","region":"us_west"}`
+	exp := `[{"metric":"cpu.n","timestamp":1000,"value":1,` + tags + `},` +
+		`{"metric":"cpu.f","timestamp":1000,"value":1.5,` + tags + `},` +
+		`{"metric":"cpu.b","timestamp":1000,"value":1,` + tags + `}]`
+	if got != exp {
+		t.Errorf("Wrong body. got %s, exp %s", got, exp)
+	}
+}
+
+func TestOpenTSDBClient_Telnet(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := write.NewOpenTSDBClient(write.ClientConfig{BaseURL: "tcp://" + l.Addr().String(), Precision: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, _, _, err := c.Send([]byte(openTSDBBatch)); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	got := []string{}
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		got = append(got, s.Text())
+	}
+
+	exp := []string{
+		"put cpu.n 1000 1 host=a region=us_west",
+		"put cpu.f 1000 1.5 host=a region=us_west",
+		"put cpu.b 1000 1 host=a region=us_west",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong lines sent. got %q, exp %q", got, exp)
+	}
+}
+
+func TestOpenTSDBClient_AppendPoint(t *testing.T) {
+	var got []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Invalid JSON body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c, err := write.NewOpenTSDBClient(write.ClientConfig{BaseURL: ts.URL, Precision: "ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, ok := c.(write.Encoder)
+	if !ok {
+		t.Fatal("Expected the points to be encoded without line protocol")
+	}
+
+	pts, err := point.NewPoints("cpu,host=server", `n=1i,f=1.5,s="x"`, 2, point.Config{Precision: lineprotocol.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf []byte
+	for _, pt := range pts {
+		pt.SetTime(time.Unix(1, 0))
+		if buf, err = enc.AppendPoint(buf, pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, _, err := enc.SendEncoded(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("Wrong number of data points sent. got %v, exp %v", len(got), 4)
+	}
+	for i, exp := range []string{"cpu.n", "cpu.f", "cpu.n", "cpu.f"} {
+		if got[i]["metric"] != exp || got[i]["timestamp"] != 1000.0 {
+			t.Errorf("Wrong data point %d. got %v, exp metric %v at 1000", i, got[i], exp)
+		}
+	}
+}
+
+func TestOpenTSDBClient_Write(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p := write.FormatPrecision("opentsdb")
+	c, err := write.NewOpenTSDBClient(write.ClientConfig{BaseURL: "tcp://" + l.Addr().String(), Precision: p.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.(write.Encoder); !ok {
+		t.Fatal("Expected the points to be encoded without line protocol")
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pts, err := point.NewPoints("cpu,host=server", "n=0i", 2, point.Config{Precision: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The batches have more points than series, and are written at once.
+	tick := make(chan time.Time, 2)
+	tick <- time.Now()
+	tick <- time.Now()
+	cfg := stress.WriteConfig{
+		BatchSize: 10,
+		MaxPoints: 20,
+		Precision: p,
+		Deadline:  time.Now().Add(time.Minute),
+		Tick:      tick,
+		Results:   make(chan stress.WriteResult, 10),
+	}
+	if n, _ := stress.Write(pts, c, cfg); n != 20 {
+		t.Fatalf("Wrong number of points written. got %v, exp %v", n, 20)
+	}
+	c.Close()
+
+	seen := map[string]bool{}
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		// put metric timestamp value tags
+		parts := strings.Fields(s.Text())
+		sample := parts[1] + " " + strings.Join(parts[4:], " ") + " " + parts[2]
+		if seen[sample] {
+			t.Fatalf("Data point %s was written twice", sample)
+		}
+		seen[sample] = true
+	}
+	if got, exp := len(seen), 20; got != exp {
+		t.Errorf("Wrong number of data points written. got %v, exp %v", got, exp)
+	}
+}
+
+func TestNewOpenTSDBClient_invalid(t *testing.T) {
+	if _, err := write.NewOpenTSDBClient(write.ClientConfig{BaseURL: "udp://localhost:4242"}); err == nil {
+		t.Error("Expected an error creating an OpenTSDB client over UDP")
+	}
+}
+
+func BenchmarkOpenTSDBClient_AppendPoint(b *testing.B) {
+	c, err := write.NewOpenTSDBClient(write.ClientConfig{BaseURL: "http://localhost:4242", Precision: "ms"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+
+	benchmarkAppendPoint(b, c)
+}
@@ -0,0 +1,256 @@
+package write
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influx-stress/lineprotocol"
+	"github.com/klauspost/compress/s2"
+)
+
+// DefaultRemoteWritePath is the path of the remote write endpoint of a host
+// given without one.
+const DefaultRemoteWritePath = "/api/v1/write"
+
+// prometheusClient writes the points as Prometheus remote write requests,
+// with a time series per numeric field named measurement_field and labelled
+// with the tags of the point. Batches of line protocol are converted.
+type prometheusClient struct {
+	*client
+
+	precision lineprotocol.Precision
+}
+
+// NewPrometheusClient returns a Client sending remote write requests to
+// cfg.BaseURL, or to its DefaultRemoteWritePath if it has no path or is the
+// address of a unix socket. Timestamps are in milliseconds and string fields
+// are not sent.
+func NewPrometheusClient(cfg ClientConfig) (Client, error) {
+	p, err := lineprotocol.ParsePrecision(cfg.Precision)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	writeURL := cfg.BaseURL
+	if u.Scheme == "unix" || u.Path == "" || u.Path == "/" {
+		writeURL = strings.TrimSuffix(httpBaseURL(cfg.BaseURL), "/") + DefaultRemoteWritePath
+	}
+
+	c := &prometheusClient{client: newClient(cfg, writeURL), precision: p}
+	c.contentType = []byte("application/x-protobuf")
+	c.header = map[string]string{
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	}
+
+	return c, nil
+}
+
+// Create does nothing, remote write has no databases.
+func (c *prometheusClient) Create(command string) error {
+	return nil
+}
+
+// Send converts the batch b of line protocol and sends it.
+func (c *prometheusClient) Send(b []byte) (latNs int64, statusCode int, body string, err error) {
+	pts, err := lineprotocol.ParsePoints(b, c.precision)
+	if err != nil {
+		return 0, -1, "", err
+	}
+
+	req := make([]byte, 0, len(b))
+	for _, pt := range pts {
+		req = appendTimeSeries(req, pt.Measurement, pt.Tags, pt.Fields(), pt.Time().Time())
+	}
+
+	return c.SendEncoded(req)
+}
+
+// AppendPoint appends the time series of the fields of p to the
+// WriteRequest in buf.
+func (c *prometheusClient) AppendPoint(buf []byte, p lineprotocol.Point) ([]byte, error) {
+	var (
+		tagsBuf   [16]lineprotocol.Tag
+		fieldsBuf [16]lineprotocol.Field
+	)
+	measurement, tags, err := lineprotocol.SplitSeries(p.Series(), tagsBuf[:0])
+	if err != nil {
+		return buf, err
+	}
+
+	return appendTimeSeries(buf, measurement, tags, lineprotocol.WrittenFields(fieldsBuf[:0], p), p.Time().Time()), nil
+}
+
+// SendEncoded compresses and sends the WriteRequest appended to b by
+// AppendPoint.
+func (c *prometheusClient) SendEncoded(b []byte) (latNs int64, statusCode int, body string, err error) {
+	if len(b) == 0 {
+		return 0, http.StatusNoContent, "", nil
+	}
+
+	return c.client.Send(s2.EncodeSnappy(nil, b))
+}
+
+// promLabel is a label of a time series, named names[start:end] in the
+// names of its labels. The value of __name__ is the metric name.
+type promLabel struct {
+	start, end int
+	value      []byte
+}
+
+// appendTimeSeries appends to buf the protobuf encoding of the time series
+// of a sample per numeric field of a point, as the timeseries fields of a
+// WriteRequest. A tag whose label name is taken, by __name__ or by another
+// tag once both are sanitized, is renamed with trailing underscores.
+func appendTimeSeries(buf, measurement []byte, tags []lineprotocol.Tag, fields []lineprotocol.Field, t time.Time) []byte {
+	var (
+		labelsBuf [17]promLabel
+		namesBuf  [256]byte
+		metricBuf [64]byte
+		seriesBuf [512]byte
+		msgBuf    [128]byte
+	)
+
+	names := append(namesBuf[:0], "__name__"...)
+	labels := append(labelsBuf[:0], promLabel{end: len(names)})
+	for _, tag := range tags {
+		start := len(names)
+		names = appendPromName(names, tag.Key)
+		for hasPromLabel(labels, names, names[start:]) {
+			names = append(names, '_')
+		}
+		labels = append(labels, promLabel{start: start, end: len(names), value: tag.Value})
+	}
+	sortPromLabels(labels, names)
+
+	ms := t.UnixNano() / 1e6
+	for _, f := range fields {
+		key, v, ok := fieldFloat(f)
+		if !ok {
+			continue
+		}
+
+		metric := appendPromName(metricBuf[:0], measurement)
+		metric = append(metric, '_')
+		metric = appendPromName(metric, key)
+
+		// TimeSeries: labels = 1, samples = 2.
+		series := seriesBuf[:0]
+		msg := msgBuf[:0]
+		for _, l := range labels {
+			value := l.value
+			if l.start == 0 {
+				value = metric
+			}
+
+			// Label: name = 1, value = 2.
+			msg = appendProtoBytes(msg[:0], 1, names[l.start:l.end])
+			msg = appendProtoBytes(msg, 2, value)
+			series = appendProtoBytes(series, 1, msg)
+		}
+
+		// Sample: value = 1 as a double, timestamp = 2 as an int64.
+		msg = append(msg[:0], 1<<3|1)
+		msg = append(msg, make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(msg[1:], math.Float64bits(v))
+		msg = append(msg, 2<<3)
+		msg = appendVarint(msg, uint64(ms))
+		series = appendProtoBytes(series, 2, msg)
+
+		// WriteRequest: timeseries = 1.
+		buf = appendProtoBytes(buf, 1, series)
+	}
+
+	return buf
+}
+
+// hasPromLabel returns whether one of labels is named name.
+func hasPromLabel(labels []promLabel, names, name []byte) bool {
+	for _, l := range labels {
+		if bytes.Equal(names[l.start:l.end], name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortPromLabels sorts the few labels of a time series by name.
+func sortPromLabels(labels []promLabel, names []byte) {
+	for i := 1; i < len(labels); i++ {
+		for j := i; j > 0; j-- {
+			if bytes.Compare(names[labels[j].start:labels[j].end], names[labels[j-1].start:labels[j-1].end]) >= 0 {
+				break
+			}
+			labels[j], labels[j-1] = labels[j-1], labels[j]
+		}
+	}
+}
+
+// appendProtoBytes appends a length delimited field, such as a string or
+// an embedded message, to buf.
+func appendProtoBytes(buf []byte, field int, b []byte) []byte {
+	buf = appendVarint(buf, uint64(field)<<3|2)
+	buf = appendVarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// appendVarint appends v as a base 128 varint.
+func appendVarint(dst []byte, v uint64) []byte {
+	for v >= 0x80 {
+		dst = append(dst, byte(v)|0x80)
+		v >>= 7
+	}
+
+	return append(dst, byte(v))
+}
+
+// appendPromName appends b to buf as a valid metric or label name, with
+// the characters other than letters, digits and underscores replaced by
+// underscores, and a leading digit prefixed by one.
+func appendPromName(buf, b []byte) []byte {
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				buf = append(buf, '_')
+			}
+		default:
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+
+	return buf
+}
+
+// fieldFloat returns the key and the value as a float of a numeric or
+// boolean field, with booleans as 1 or 0. ok is false for string fields.
+func fieldFloat(f lineprotocol.Field) (key []byte, v float64, ok bool) {
+	switch f := f.(type) {
+	case *lineprotocol.Int:
+		return f.Key, float64(f.Value), true
+	case *lineprotocol.Uint:
+		return f.Key, float64(f.Value), true
+	case *lineprotocol.Float:
+		return f.Key, f.Value, true
+	case *lineprotocol.Bool:
+		if f.Value {
+			return f.Key, 1, true
+		}
+		return f.Key, 0, true
+	}
+
+	return nil, 0, false
+}
@@ -0,0 +1,245 @@
+package write_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-stress/point"
+	"github.com/influxdata/influx-stress/stress"
+	"github.com/influxdata/influx-stress/write"
+	"github.com/klauspost/compress/s2"
+)
+
+// promServer records the decoded bodies of the remote write requests it receives.
+type promServer struct {
+	t      *testing.T
+	bodies [][]byte
+	sizes  []int
+}
+
+func (s *promServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for k, exp := range map[string]string{
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	} {
+		if got := r.Header.Get(k); got != exp {
+			s.t.Errorf("Wrong %s header. got %v, exp %v", k, got, exp)
+		}
+	}
+
+	if r.URL.Path != "/api/v1/write" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	b, _ := ioutil.ReadAll(r.Body)
+	body, err := s2.Decode(nil, b)
+	if err != nil {
+		s.t.Errorf("Invalid snappy body: %v", err)
+	}
+	s.bodies = append(s.bodies, body)
+	s.sizes = append(s.sizes, len(b))
+	w.WriteHeader(http.StatusOK)
+}
+
+// promSamples returns the samples of a write request, as their labels and
+// timestamp.
+func promSamples(t *testing.T, req []byte) []string {
+	// next returns the length delimited field at the start of b and the rest of b.
+	next := func(b []byte) (field uint64, v, rest []byte) {
+		key, n := binary.Uvarint(b)
+		l, m := binary.Uvarint(b[n:])
+		if key&7 != 2 || n <= 0 || m <= 0 || uint64(len(b)) < uint64(n+m)+l {
+			t.Fatalf("Invalid protobuf %q", b)
+		}
+		return key >> 3, b[n+m : n+m+int(l)], b[n+m+int(l):]
+	}
+
+	samples := []string{}
+	for len(req) > 0 {
+		var series []byte
+		_, series, req = next(req)
+
+		labels := ""
+		for len(series) > 0 {
+			field, v, rest := next(series)
+			series = rest
+			switch field {
+			case 1:
+				_, name, rest := next(v)
+				_, value, _ := next(rest)
+				labels += string(name) + "=" + string(value) + ","
+			case 2:
+				ts, _ := binary.Uvarint(v[10:])
+				samples = append(samples, labels+" "+strconv.FormatUint(ts, 10))
+			}
+		}
+	}
+
+	return samples
+}
+
+// pb returns the protobuf encoding of a length delimited field holding the
+// concatenation of values.
+func pb(field byte, values ...string) string {
+	v := strings.Join(values, "")
+	return string([]byte{field<<3 | 2, byte(len(v))}) + v
+}
+
+func TestPrometheusClient(t *testing.T) {
+	s := &promServer{t: t}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := write.NewPrometheusClient(write.ClientConfig{BaseURL: ts.URL, Precision: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Create(""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, status, _, err := c.Send([]byte("cpu,host=a,Zone=z n=1i,s=\"x\" 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusOK)
+	}
+
+	// The labels are sorted, the value 1 is the double 0x3ff0000000000000
+	// and the timestamp 1000 is the varint e8 07.
+	sample := "\x09\x00\x00\x00\x00\x00\x00\xf0\x3f\x10\xe8\x07"
+	exp := pb(1,
+		pb(1, pb(1, "Zone"), pb(2, "z")),
+		pb(1, pb(1, "__name__"), pb(2, "cpu_n")),
+		pb(1, pb(1, "host"), pb(2, "a")),
+		pb(2, sample),
+	)
+	if len(s.bodies) != 1 || string(s.bodies[0]) != exp {
+		t.Errorf("Wrong write request. got %q, exp %q", s.bodies, exp)
+	}
+}
+
+func TestPrometheusClient_ClashingLabels(t *testing.T) {
+	s := &promServer{t: t}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := write.NewPrometheusClient(write.ClientConfig{BaseURL: ts.URL, Precision: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := c.Send([]byte("cpu,__name__=x,host-name=a,host_name=b n=1i 1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"__name__=cpu_n,__name___=x,host_name=a,host_name_=b, 1000"}
+	if len(s.bodies) != 1 {
+		t.Fatalf("Wrong number of write requests. got %v, exp %v", len(s.bodies), 1)
+	}
+	if got := promSamples(t, s.bodies[0]); fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("Wrong samples written. got %v, exp %v", got, exp)
+	}
+}
+
+func TestPrometheusClient_Write(t *testing.T) {
+	s := &promServer{t: t}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	p := write.FormatPrecision("prometheus")
+	c, err := write.NewPrometheusClient(write.ClientConfig{BaseURL: ts.URL, Precision: p.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.(write.Encoder); !ok {
+		t.Fatal("Expected the points to be encoded without line protocol")
+	}
+
+	pts, err := point.NewPoints("cpu,host=server", "n=0i", 2, point.Config{Precision: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The batches have more points than series, and are written at once.
+	tick := make(chan time.Time, 2)
+	tick <- time.Now()
+	tick <- time.Now()
+	cfg := stress.WriteConfig{
+		BatchSize: 10,
+		MaxPoints: 20,
+		Precision: p,
+		Deadline:  time.Now().Add(time.Minute),
+		Tick:      tick,
+		Results:   make(chan stress.WriteResult, 10),
+	}
+	if n, _ := stress.Write(pts, c, cfg); n != 20 {
+		t.Fatalf("Wrong number of points written. got %v, exp %v", n, 20)
+	}
+
+	seen := map[string]bool{}
+	for _, body := range s.bodies {
+		for _, sample := range promSamples(t, body) {
+			if seen[sample] {
+				t.Fatalf("Sample %s was written twice", sample)
+			}
+			seen[sample] = true
+		}
+	}
+	if got, exp := len(seen), 20; got != exp {
+		t.Errorf("Wrong number of samples written. got %v, exp %v", got, exp)
+	}
+}
+
+func TestPrometheusClient_Compression(t *testing.T) {
+	s := &promServer{t: t}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := write.NewPrometheusClient(write.ClientConfig{BaseURL: ts.URL + "/", Precision: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Larger than a snappy block.
+	batch := bytes.NewBuffer(nil)
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(batch, "cpu,host=server-%d usage=%d.5,idle=true %d\n", i%100, i, i)
+	}
+
+	if _, _, _, err := c.Send(batch.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.bodies) != 1 {
+		t.Fatalf("Expected a request, got %d", len(s.bodies))
+	}
+	if got, exp := bytes.Count(s.bodies[0], []byte("__name__")), 10000; got != exp {
+		t.Errorf("Wrong number of time series. got %d, exp %d", got, exp)
+	}
+	if len(s.bodies[0]) < 1<<16 || s.sizes[0] > len(s.bodies[0])/2 {
+		t.Errorf("Expected the body of %d bytes to be compressed, got %d bytes", len(s.bodies[0]), s.sizes[0])
+	}
+}
+
+func BenchmarkPrometheusClient_AppendPoint(b *testing.B) {
+	c, err := write.NewPrometheusClient(write.ClientConfig{BaseURL: "http://localhost:9090", Precision: "ms"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+
+	benchmarkAppendPoint(b, c)
+}
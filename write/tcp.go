@@ -0,0 +1,133 @@
+package write
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// tcpClient streams line protocol over persistent TCP connections, to
+// listeners such as the socket_listener input of Telegraf. Every concurrent
+// Send writes to a connection of its own, kept open for the next batches and
+// reopened when it breaks.
+type tcpClient struct {
+	addr string
+
+	mu     sync.Mutex
+	idle   []net.Conn
+	closed bool
+}
+
+// NewTCPClient returns a Client connected to the host and port of
+// cfg.BaseURL, such as tcp://localhost:8094.
+func NewTCPClient(cfg ClientConfig) (Client, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host and port in TCP address " + cfg.BaseURL)
+	}
+
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tcpClient{addr: u.Host, idle: []net.Conn{conn}}, nil
+}
+
+// Create does nothing, the database of a socket listener is set in its configuration.
+func (c *tcpClient) Create(command string) error {
+	return nil
+}
+
+// Send writes the batch b to an idle connection, or to a new one if every
+// connection is in use. If that fails, the connection is reopened and the
+// lines of b that were not entirely written are written again once. The lines
+// written before the failure may be lost with the connection, and the one
+// being written may arrive truncated, but no line is written twice. Nothing
+// is received back, so the status code is 204 when b was written and -1
+// otherwise.
+func (c *tcpClient) Send(b []byte) (latNs int64, statusCode int, body string, err error) {
+	statusCode = -1
+	start := time.Now()
+	defer func() {
+		latNs = time.Since(start).Nanoseconds()
+	}()
+
+	conn, err := c.get()
+	if err != nil {
+		return
+	}
+
+	n, err := conn.Write(b)
+	if err == nil {
+		c.put(conn)
+		statusCode = http.StatusNoContent
+		return
+	}
+	conn.Close()
+
+	b = b[bytes.LastIndexByte(b[:n], '\n')+1:]
+	if conn, err = net.Dial("tcp", c.addr); err != nil {
+		return
+	}
+
+	if _, err = conn.Write(b); err != nil {
+		conn.Close()
+		return
+	}
+	c.put(conn)
+
+	statusCode = http.StatusNoContent
+	return
+}
+
+// get returns an idle connection, or a new one if there is none.
+func (c *tcpClient) get() (net.Conn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	return net.Dial("tcp", c.addr)
+}
+
+// put makes conn idle, or closes it if the client is closed.
+func (c *tcpClient) put(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// Close closes the idle connections, and the ones in use once their Send
+// returns.
+func (c *tcpClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for _, conn := range c.idle {
+		if cerr := conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	c.idle = nil
+	c.closed = true
+
+	return err
+}
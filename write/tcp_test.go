@@ -0,0 +1,156 @@
+package write_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-stress/write"
+)
+
+func TestTCPClient(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Every connection accepted sends its lines to lines.
+	conns := make(chan net.Conn, 2)
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				s := bufio.NewScanner(conn)
+				for s.Scan() {
+					lines <- s.Text()
+				}
+			}()
+		}
+	}()
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a line")
+			return ""
+		}
+	}
+
+	c, err := write.NewTCPClient(write.ClientConfig{BaseURL: "tcp://" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, status, _, err := c.Send([]byte("cpu n=1i 1\ncpu n=2i 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusNoContent)
+	}
+
+	for _, exp := range []string{"cpu n=1i 1", "cpu n=2i 2"} {
+		if got := next(); got != exp {
+			t.Errorf("Wrong line received. got %q, exp %q", got, exp)
+		}
+	}
+
+	// Once the listener drops the connection, writes that are not lost
+	// with it go through a new one.
+	(<-conns).Close()
+	for i := 0; len(conns) == 0; i++ {
+		if i == 100 {
+			t.Fatal("The client did not reconnect")
+		}
+		c.Send([]byte("cpu n=3i 3\n"))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got, exp := next(), "cpu n=3i 3"; got != exp {
+		t.Errorf("Wrong line received after reconnecting. got %q, exp %q", got, exp)
+	}
+}
+
+func TestTCPClient_Concurrent(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var conns int32
+	lines := make(chan string, 200)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&conns, 1)
+			go func() {
+				s := bufio.NewScanner(conn)
+				for s.Scan() {
+					lines <- s.Text()
+				}
+			}()
+		}
+	}()
+
+	c, err := write.NewTCPClient(write.ClientConfig{BaseURL: "tcp://" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, _, _, err := c.Send([]byte(fmt.Sprintf("cpu,writer=%d n=%di\n", i, j))); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	c.Close()
+
+	seen := map[string]bool{}
+	for len(seen) < 200 {
+		select {
+		case line := <-lines:
+			if seen[line] {
+				t.Fatalf("Line %q received twice", line)
+			}
+			seen[line] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for the lines, got %d", len(seen))
+		}
+	}
+
+	// Every writer uses at most one connection at a time.
+	if n := atomic.LoadInt32(&conns); n > 4 {
+		t.Errorf("Too many connections opened. got %d, exp at most 4", n)
+	}
+}
+
+func TestNewTCPClient_invalid(t *testing.T) {
+	if _, err := write.NewTCPClient(write.ClientConfig{BaseURL: "tcp://"}); err == nil {
+		t.Error("Expected an error creating a TCP client without a host")
+	}
+}
@@ -0,0 +1,117 @@
+package write
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultUDPPayload is the default maximum size of the packets of the UDP
+// client, small enough not to be fragmented on any network.
+const DefaultUDPPayload = 512
+
+// PacketCounter is implemented by the clients of transports without
+// responses, such as UDP, to report what they sent instead.
+type PacketCounter interface {
+	Packets() uint64
+	Bytes() uint64
+}
+
+// udpClient writes line protocol to a UDP listener, such as the UDP input of
+// InfluxDB or Telegraf. Batches are split on line boundaries into packets of
+// at most payload bytes.
+type udpClient struct {
+	conn    net.Conn
+	payload int
+
+	packets uint64
+	bytes   uint64
+}
+
+// NewUDPClient returns a Client sending to the host and port of cfg.BaseURL,
+// such as udp://localhost:8089, in packets of at most cfg.UDPPayload bytes.
+func NewUDPClient(cfg ClientConfig) (Client, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host and port in UDP address " + cfg.BaseURL)
+	}
+
+	conn, err := net.Dial("udp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := cfg.UDPPayload
+	if payload <= 0 {
+		payload = DefaultUDPPayload
+	}
+
+	return &udpClient{conn: conn, payload: payload}, nil
+}
+
+// Create does nothing, the database of a UDP listener is set in its configuration.
+func (c *udpClient) Create(command string) error {
+	return nil
+}
+
+// Send sends the batch b. Nothing is received back, so the status code is
+// always 204 unless sending failed.
+func (c *udpClient) Send(b []byte) (latNs int64, statusCode int, body string, err error) {
+	start := time.Now()
+	defer func() {
+		latNs = time.Since(start).Nanoseconds()
+	}()
+
+	for len(b) > 0 {
+		n := packetLen(b, c.payload)
+		if _, err = c.conn.Write(b[:n]); err != nil {
+			return
+		}
+		atomic.AddUint64(&c.packets, 1)
+		atomic.AddUint64(&c.bytes, uint64(n))
+		b = b[n:]
+	}
+
+	statusCode = http.StatusNoContent
+	return
+}
+
+// Packets returns the number of packets sent.
+func (c *udpClient) Packets() uint64 {
+	return atomic.LoadUint64(&c.packets)
+}
+
+// Bytes returns the number of bytes sent.
+func (c *udpClient) Bytes() uint64 {
+	return atomic.LoadUint64(&c.bytes)
+}
+
+func (c *udpClient) Close() error {
+	return c.conn.Close()
+}
+
+// packetLen returns the length of the first packet of b, made of as many
+// whole lines as fit in size bytes, or of the first line alone if it is
+// longer than size.
+func packetLen(b []byte, size int) int {
+	n := 0
+	for n < len(b) {
+		end := len(b)
+		if i := bytes.IndexByte(b[n:], '\n'); i >= 0 {
+			end = n + i + 1
+		}
+		if end > size && n > 0 {
+			break
+		}
+		n = end
+	}
+
+	return n
+}
@@ -0,0 +1,66 @@
+package write_test
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influx-stress/write"
+)
+
+func TestUDPClient(t *testing.T) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := write.NewUDPClient(write.ClientConfig{BaseURL: "udp://" + l.LocalAddr().String(), UDPPayload: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Create(""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The third line is longer than the payload, so it is sent alone.
+	batch := "cpu n=1i 1\ncpu n=2i 2\ncpu,host=server01 n=3i 3\ncpu n=4i 4\n"
+	_, status, _, err := c.Send([]byte(batch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusNoContent)
+	}
+
+	exp := []string{"cpu n=1i 1\ncpu n=2i 2\n", "cpu,host=server01 n=3i 3\n", "cpu n=4i 4\n"}
+	got := []string{}
+	buf := make([]byte, 1024)
+	l.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for range exp {
+		n, _, err := l.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong packets received. got %q, exp %q", got, exp)
+	}
+
+	pc := c.(write.PacketCounter)
+	if pc.Packets() != 3 || pc.Bytes() != uint64(len(batch)) {
+		t.Errorf("Wrong counts. got %d packets and %d bytes, exp 3 and %d", pc.Packets(), pc.Bytes(), len(batch))
+	}
+}
+
+func TestNewUDPClient_invalid(t *testing.T) {
+	if _, err := write.NewUDPClient(write.ClientConfig{BaseURL: "udp://"}); err == nil {
+		t.Error("Expected an error creating a UDP client without a host")
+	}
+}
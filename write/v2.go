@@ -0,0 +1,161 @@
+package write
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// v2Client writes to the v2 API of InfluxDB 2.x. It sends its writes like
+// the v1 client, to /api/v2/write with a token, and creates buckets
+// instead of databases.
+type v2Client struct {
+	*client
+}
+
+// NewV2Client returns a Client writing to cfg.Bucket in cfg.Org through the
+// v2 API, authenticated with cfg.Token.
+func NewV2Client(cfg ClientConfig) (Client, error) {
+	if cfg.Org == "" || cfg.Bucket == "" {
+		return nil, errors.New("the v2 API needs an org and a bucket")
+	}
+
+	if _, err := v2Precision(cfg.Precision); err != nil {
+		return nil, err
+	}
+
+	c := &v2Client{client: newClient(cfg, v2WriteURL(cfg))}
+	if cfg.Token != "" {
+		c.authorization = []byte("Token " + cfg.Token)
+	}
+
+	return c, nil
+}
+
+// Create creates the bucket in the org, unless it already exists. The v2 API
+// has no create commands, so command must be empty.
+func (c *v2Client) Create(command string) error {
+	if command != "" {
+		return fmt.Errorf("create command %q is not supported by the v2 API", command)
+	}
+
+	orgID, err := c.orgID()
+	if err != nil {
+		return err
+	}
+
+	bucket, err := json.Marshal(map[string]interface{}{
+		"orgID":          orgID,
+		"name":           c.cfg.Bucket,
+		"retentionRules": []interface{}{},
+	})
+	if err != nil {
+		return err
+	}
+
+	status, body, err := c.do("POST", "/api/v2/buckets", bytes.NewReader(bucket))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status == http.StatusCreated:
+		return nil
+	case (status == http.StatusUnprocessableEntity || status == http.StatusConflict) && bytes.Contains(body, []byte("already exists")):
+		return nil
+	}
+
+	return fmt.Errorf(
+		"Bad status code during Create(%s): %d, body: %s",
+		c.cfg.Bucket, status, string(body),
+	)
+}
+
+// orgID looks up the ID of the org.
+func (c *v2Client) orgID() (string, error) {
+	status, body, err := c.do("GET", "/api/v2/orgs?"+url.Values{"org": {c.cfg.Org}}.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if status != http.StatusOK {
+		return "", fmt.Errorf("Bad status code looking up org %s: %d, body: %s", c.cfg.Org, status, string(body))
+	}
+
+	var resp struct {
+		Orgs []struct {
+			ID string `json:"id"`
+		} `json:"orgs"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Orgs) == 0 {
+		return "", fmt.Errorf("org %s not found", c.cfg.Org)
+	}
+
+	return resp.Orgs[0].ID, nil
+}
+
+// do sends a request with the token to the API and returns the response.
+func (c *v2Client) do(method, path string, body io.Reader) (int, []byte, error) {
+	return apiRequest(c.api, method, httpBaseURL(c.cfg.BaseURL)+path, c.authorization, body)
+}
+
+// apiRequest sends a request with a JSON body, if any, and the given
+// Authorization header to an API, and returns the status and body of the response.
+func apiRequest(hc *http.Client, method, u string, authorization []byte, body io.Reader) (int, []byte, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(authorization) > 0 {
+		req.Header.Set("Authorization", string(authorization))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, b, err
+}
+
+// v2Precision returns the v2 API name of the precision p.
+func v2Precision(p string) (string, error) {
+	switch p {
+	case "", "n":
+		return "ns", nil
+	case "u":
+		return "us", nil
+	case "ms", "s":
+		return p, nil
+	}
+
+	return "", fmt.Errorf("precision %q is not supported by the v2 API", p)
+}
+
+func v2WriteURL(cfg ClientConfig) string {
+	params := url.Values{}
+	params.Set("org", cfg.Org)
+	params.Set("bucket", cfg.Bucket)
+
+	p, err := v2Precision(cfg.Precision)
+	if err != nil {
+		p = cfg.Precision
+	}
+	params.Set("precision", p)
+
+	return httpBaseURL(cfg.BaseURL) + "/api/v2/write?" + params.Encode()
+}
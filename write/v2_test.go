@@ -0,0 +1,118 @@
+package write_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/influx-stress/write"
+)
+
+// v2Server is a fake of the parts of the InfluxDB 2.x API used by the v2 client.
+type v2Server struct {
+	t       *testing.T
+	buckets map[string]bool
+	writes  []string
+}
+
+func (s *v2Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if got, exp := r.Header.Get("Authorization"), "Token secret"; got != exp {
+		s.t.Errorf("Wrong authorization for %s. got %v, exp %v", r.URL.Path, got, exp)
+	}
+
+	switch r.URL.Path {
+	case "/api/v2/orgs":
+		if r.URL.Query().Get("org") != "acme" {
+			w.Write([]byte(`{"orgs":[]}`))
+			return
+		}
+		w.Write([]byte(`{"orgs":[{"id":"0123","name":"acme"}]}`))
+	case "/api/v2/buckets":
+		var bucket struct {
+			OrgID string `json:"orgID"`
+			Name  string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&bucket)
+		if bucket.OrgID != "0123" {
+			s.t.Errorf("Wrong org ID for the bucket. got %v, exp %v", bucket.OrgID, "0123")
+		}
+		if s.buckets[bucket.Name] {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code":"conflict","message":"bucket with name stress already exists"}`))
+			return
+		}
+		s.buckets[bucket.Name] = true
+		w.WriteHeader(http.StatusCreated)
+	case "/api/v2/write":
+		b, _ := ioutil.ReadAll(r.Body)
+		s.writes = append(s.writes, r.URL.RawQuery+" "+string(b))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestV2Client(t *testing.T) {
+	s := &v2Server{t: t, buckets: map[string]bool{}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := write.NewV2Client(write.ClientConfig{
+		BaseURL:   ts.URL,
+		Precision: "s",
+		API:       "v2",
+		Org:       "acme",
+		Bucket:    "stress",
+		Token:     "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating a bucket that already exists is not an error.
+	for i := 0; i < 2; i++ {
+		if err := c.Create(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, status, _, err := c.Send([]byte("cpu n=1i 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusNoContent)
+	}
+
+	if exp := []string{"bucket=stress&org=acme&precision=s cpu n=1i 1\n"}; len(s.writes) != 1 || s.writes[0] != exp[0] {
+		t.Errorf("Wrong writes received. got %q, exp %q", s.writes, exp)
+	}
+}
+
+func TestV2Client_UnknownOrg(t *testing.T) {
+	ts := httptest.NewServer(&v2Server{t: t, buckets: map[string]bool{}})
+	defer ts.Close()
+
+	c, err := write.NewV2Client(write.ClientConfig{BaseURL: ts.URL, Org: "other", Bucket: "stress", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Create(""); err == nil {
+		t.Error("Expected an error creating a bucket in an unknown org")
+	}
+}
+
+func TestNewV2Client_invalid(t *testing.T) {
+	for _, cfg := range []write.ClientConfig{
+		{Bucket: "stress"},
+		{Org: "acme"},
+		{Org: "acme", Bucket: "stress", Precision: "h"},
+	} {
+		if _, err := write.NewV2Client(cfg); err == nil {
+			t.Errorf("Expected an error creating a v2 client with %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package write
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// v3Client writes to the /api/v3/write_lp endpoint of InfluxDB 3, with a
+// bearer token, and creates databases through the configure API.
+type v3Client struct {
+	*client
+}
+
+// NewV3Client returns a Client writing to cfg.Database through the v3 API,
+// authenticated with cfg.Token.
+func NewV3Client(cfg ClientConfig) (Client, error) {
+	if _, err := v3Precision(cfg.Precision); err != nil {
+		return nil, err
+	}
+
+	c := &v3Client{client: newClient(cfg, v3WriteURL(cfg))}
+	if cfg.Token != "" {
+		c.authorization = []byte("Bearer " + cfg.Token)
+	}
+
+	return c, nil
+}
+
+// Create creates the database, unless it already exists. The v3 API has no
+// create commands, so command must be empty.
+func (c *v3Client) Create(command string) error {
+	if command != "" {
+		return fmt.Errorf("create command %q is not supported by the v3 API", command)
+	}
+
+	db, err := json.Marshal(map[string]string{"db": c.cfg.Database})
+	if err != nil {
+		return err
+	}
+
+	status, body, err := apiRequest(c.api, "POST", httpBaseURL(c.cfg.BaseURL)+"/api/v3/configure/database", c.authorization, bytes.NewReader(db))
+	if err != nil {
+		return err
+	}
+
+	if status/100 == 2 || status == http.StatusConflict {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"Bad status code during Create(%s): %d, body: %s",
+		c.cfg.Database, status, string(body),
+	)
+}
+
+// Send writes the batch b. When some lines of the batch are rejected, the
+// body returned lists them.
+func (c *v3Client) Send(b []byte) (latNs int64, statusCode int, body string, err error) {
+	latNs, statusCode, body, err = c.client.Send(b)
+	if err == nil && statusCode != http.StatusNoContent {
+		body = v3ErrorBody(body)
+	}
+
+	return
+}
+
+// v3ErrorBody returns a readable summary of an error response of the v3 API,
+// or body itself if it is not one.
+func v3ErrorBody(body string) string {
+	var resp struct {
+		Error string `json:"error"`
+		Data  []struct {
+			OriginalLine string `json:"original_line"`
+			LineNumber   int    `json:"line_number"`
+			ErrorMessage string `json:"error_message"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil || resp.Error == "" {
+		return body
+	}
+
+	msgs := []string{resp.Error}
+	for _, d := range resp.Data {
+		msgs = append(msgs, fmt.Sprintf("line %d: %s: %s", d.LineNumber, d.ErrorMessage, d.OriginalLine))
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// v3Precision returns the v3 API name of the precision p.
+func v3Precision(p string) (string, error) {
+	switch p {
+	case "", "n":
+		return "nanosecond", nil
+	case "u":
+		return "microsecond", nil
+	case "ms":
+		return "millisecond", nil
+	case "s":
+		return "second", nil
+	}
+
+	return "", fmt.Errorf("precision %q is not supported by the v3 API", p)
+}
+
+func v3WriteURL(cfg ClientConfig) string {
+	params := url.Values{}
+	params.Set("db", cfg.Database)
+
+	p, err := v3Precision(cfg.Precision)
+	if err != nil {
+		p = cfg.Precision
+	}
+	params.Set("precision", p)
+	params.Set("accept_partial", strconv.FormatBool(cfg.AcceptPartial))
+	params.Set("no_sync", strconv.FormatBool(cfg.NoSync))
+
+	return httpBaseURL(cfg.BaseURL) + "/api/v3/write_lp?" + params.Encode()
+}
@@ -0,0 +1,117 @@
+package write_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/influx-stress/write"
+)
+
+// v3Server is a fake of the parts of the InfluxDB 3 API used by the v3 client.
+type v3Server struct {
+	t      *testing.T
+	dbs    map[string]bool
+	writes []string
+}
+
+func (s *v3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if got, exp := r.Header.Get("Authorization"), "Bearer secret"; got != exp {
+		s.t.Errorf("Wrong authorization for %s. got %v, exp %v", r.URL.Path, got, exp)
+	}
+
+	switch r.URL.Path {
+	case "/api/v3/configure/database":
+		var db struct {
+			Name string `json:"db"`
+		}
+		json.NewDecoder(r.Body).Decode(&db)
+		if s.dbs[db.Name] {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"attempted to create a resource that already exists"}`))
+			return
+		}
+		s.dbs[db.Name] = true
+		w.WriteHeader(http.StatusOK)
+	case "/api/v3/write_lp":
+		b, _ := ioutil.ReadAll(r.Body)
+		s.writes = append(s.writes, r.URL.RawQuery+" "+string(b))
+		if string(b) == "cpu n=\n" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"partial write of line protocol occurred","data":[{"original_line":"cpu n=","line_number":1,"error_message":"invalid field value"}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestV3Client(t *testing.T) {
+	s := &v3Server{t: t, dbs: map[string]bool{}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := write.NewV3Client(write.ClientConfig{
+		BaseURL:       ts.URL,
+		Database:      "stress",
+		Precision:     "s",
+		API:           "v3",
+		Token:         "secret",
+		AcceptPartial: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating a database that already exists is not an error.
+	for i := 0; i < 2; i++ {
+		if err := c.Create(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, status, _, err := c.Send([]byte("cpu n=1i 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusNoContent)
+	}
+
+	if exp := []string{"accept_partial=true&db=stress&no_sync=false&precision=second cpu n=1i 1\n"}; len(s.writes) != 1 || s.writes[0] != exp[0] {
+		t.Errorf("Wrong writes received. got %q, exp %q", s.writes, exp)
+	}
+}
+
+func TestV3Client_PartialWrite(t *testing.T) {
+	ts := httptest.NewServer(&v3Server{t: t, dbs: map[string]bool{}})
+	defer ts.Close()
+
+	c, err := write.NewV3Client(write.ClientConfig{BaseURL: ts.URL, Database: "stress", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, status, body, err := c.Send([]byte("cpu n=\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusBadRequest {
+		t.Errorf("Wrong status. got %v, exp %v", status, http.StatusBadRequest)
+	}
+
+	if exp := "partial write of line protocol occurred; line 1: invalid field value: cpu n="; body != exp {
+		t.Errorf("Wrong body. got %q, exp %q", body, exp)
+	}
+}
+
+func TestNewV3Client_invalid(t *testing.T) {
+	for _, p := range []string{"m", "h"} {
+		if _, err := write.NewV3Client(write.ClientConfig{Database: "stress", Precision: p}); err == nil {
+			t.Errorf("Expected an error creating a v3 client with precision %s", p)
+		}
+	}
+}